@@ -2,6 +2,7 @@ package kubernetes
 
 import (
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"k8s.io/client-go/kubernetes"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
@@ -14,3 +15,13 @@ func NewClient(d *schema.ResourceData, terraformVersion string, options client.O
 	}
 	return client.New(cfg, options)
 }
+
+// NewClientset allocates typed k8s clientset, needed for subresources
+// like pod logs which controller-runtime client doesn't support
+func NewClientset(d *schema.ResourceData, terraformVersion string) (kubernetes.Interface, error) {
+	cfg, err := GetConfig(d, terraformVersion)
+	if err != nil {
+		return nil, err
+	}
+	return kubernetes.NewForConfig(cfg)
+}
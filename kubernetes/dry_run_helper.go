@@ -0,0 +1,166 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"regexp"
+	"strings"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// rbacDeniedRe matches message of request denied by RBAC,
+// e.g. `pods is forbidden: User "dev" cannot create resource "pods" in API group ""`
+var rbacDeniedRe = regexp.MustCompile(`is forbidden: User "[^"]*" cannot [a-z]+ resource "`)
+
+// ErrDryRunValueUnknown is returned by DryRunObjectFunc when values it needs are not known at plan time
+var ErrDryRunValueUnknown = errors.New("value is not known at plan time")
+
+// DryRunObjectFunc builds the object to be created or updated from planned values.
+// It returns ErrDryRunValueUnknown when the object can't be built yet, see DryRunKnown.
+type DryRunObjectFunc func(d *schema.ResourceDiff) (runtime.Object, error)
+
+// DryRunKnown returns ErrDryRunValueUnknown when planned value of any of given keys,
+// or of any changed attribute below them, is not known yet
+func DryRunKnown(d *schema.ResourceDiff, keys ...string) error {
+	for _, key := range keys {
+		if !d.NewValueKnown(key) {
+			return fmt.Errorf("%s: %w", key, ErrDryRunValueUnknown)
+		}
+		for _, k := range d.GetChangedKeysPrefix(key) {
+			if !d.NewValueKnown(k) {
+				return fmt.Errorf("%s: %w", k, ErrDryRunValueUnknown)
+			}
+		}
+	}
+	return nil
+}
+
+// DryRunCustomizeDiff produces CustomizeDiffFunc which sends the planned object to API
+// with dryRun=All, so admission webhook rejections, quota violations and schema errors
+// are reported at plan time. Dry run is skipped when some planned values are unknown,
+// when existing object doesn't change, or when user isn't permitted to perform it.
+// Resource schema is used to detect changes of ForceNew attributes: the replacement
+// is dry run as create, as the update would fail validation of immutable fields.
+func DryRunCustomizeDiff(getClient func(meta interface{}) client.Client, resourceSchema map[string]*schema.Schema, build DryRunObjectFunc) schema.CustomizeDiffFunc {
+	return func(d *schema.ResourceDiff, m interface{}) error {
+		if d.Id() != "" && len(d.GetChangedKeysPrefix("")) == 0 {
+			return nil
+		}
+
+		obj, err := build(d)
+		if err != nil {
+			if errors.Is(err, ErrDryRunValueUnknown) {
+				log.Printf("[DEBUG] Skipping dry run of %q, %s", d.Id(), err)
+				return nil
+			}
+			return err
+		}
+		if obj == nil {
+			return nil
+		}
+
+		conn := getClient(m)
+		create := d.Id() == "" || requiresReplacement(d, resourceSchema)
+		if create {
+			log.Printf("[DEBUG] Dry run of create of %s", describeObject(obj))
+			err = conn.Create(context.TODO(), obj, client.DryRunAll)
+			// Admission and validation pass before storage reports the replaced object still exists
+			if d.Id() != "" && apierrors.IsAlreadyExists(err) {
+				err = nil
+			}
+		} else {
+			// Merge patch keeps fields set by the server, unlike full update
+			log.Printf("[DEBUG] Dry run of update of %s", describeObject(obj))
+			err = conn.Patch(context.TODO(), obj, client.Merge, client.DryRunAll)
+		}
+		if err == nil {
+			return nil
+		}
+
+		if isDryRunUnavailable(err) {
+			log.Printf("[WARN] Skipping dry run of %s: %s", describeObject(obj), err)
+			return nil
+		}
+		return fmt.Errorf("Dry run failed: %s", err)
+	}
+}
+
+// requiresReplacement tells if any of changed attributes is ForceNew.
+// Replacement forced by other CustomizeDiff functions is not detected.
+func requiresReplacement(d *schema.ResourceDiff, resourceSchema map[string]*schema.Schema) bool {
+	for _, k := range d.GetChangedKeysPrefix("") {
+		if isForceNewKey(resourceSchema, strings.Split(k, ".")) {
+			return true
+		}
+	}
+	return false
+}
+
+// isForceNewKey tells if attribute at flatmap address or any of its ancestors is ForceNew
+func isForceNewKey(fields map[string]*schema.Schema, addr []string) bool {
+	if len(addr) == 0 {
+		return false
+	}
+	s, ok := fields[addr[0]]
+	if !ok {
+		return false
+	}
+	if s.ForceNew {
+		return true
+	}
+	res, ok := s.Elem.(*schema.Resource)
+	if !ok || len(addr) < 3 {
+		return false
+	}
+	// Skip list index or set hash
+	return isForceNewKey(res.Schema, addr[2:])
+}
+
+// describeObject formats kind, namespace and name of object, not revealing its content
+func describeObject(obj runtime.Object) string {
+	kind := obj.GetObjectKind().GroupVersionKind().Kind
+	if kind == "" {
+		if gvk, err := apiutil.GVKForObject(obj, scheme.Scheme); err == nil {
+			kind = gvk.Kind
+		}
+	}
+	accessor, err := meta.Accessor(obj)
+	if err != nil {
+		return kind
+	}
+	name := accessor.GetName()
+	if name == "" {
+		name = accessor.GetGenerateName() + "*"
+	}
+	if accessor.GetNamespace() != "" {
+		name = accessor.GetNamespace() + "/" + name
+	}
+	return fmt.Sprintf("%s %s", kind, name)
+}
+
+// isDryRunUnavailable tells if the error means the dry run can't be performed,
+// as opposed to the object being rejected
+func isDryRunUnavailable(err error) bool {
+	switch {
+	case apierrors.IsForbidden(err):
+		// RBAC denial, as opposed to admission webhook or quota rejection
+		return !strings.Contains(err.Error(), "admission webhook") && rbacDeniedRe.MatchString(err.Error())
+	case apierrors.IsMethodNotSupported(err), apierrors.IsUnauthorized(err):
+		return true
+	case apierrors.IsNotFound(err):
+		// Object to update is gone, or its kind isn't known to API yet
+		return true
+	case apierrors.IsBadRequest(err):
+		return strings.Contains(strings.ToLower(err.Error()), "dryrun")
+	}
+	return false
+}
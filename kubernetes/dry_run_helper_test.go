@@ -0,0 +1,183 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const testUnknownValue = "74D93920-ED26-11E3-AC10-0800200C9A66"
+
+type dryRunRequest struct {
+	Method string
+	Path   string
+	DryRun string
+}
+
+func newDryRunTestResource(t *testing.T, responses map[string]string, requests *[]dryRunRequest) (*schema.Resource, func()) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ioutil.ReadAll(r.Body)
+		*requests = append(*requests, dryRunRequest{r.Method, r.URL.Path, r.URL.Query().Get("dryRun")})
+		w.Header().Set("Content-Type", "application/json")
+		body := responses[r.Method]
+		status := struct {
+			Code int `json:"code"`
+		}{http.StatusOK}
+		json.Unmarshal([]byte(body), &status)
+		if status.Code == 0 {
+			status.Code = http.StatusOK
+		}
+		w.WriteHeader(status.Code)
+		w.Write([]byte(body))
+	}))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(api.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	conn, err := client.New(&rest.Config{Host: srv.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	resourceSchema := map[string]*schema.Schema{
+		"metadata": NamespacedMetadataSchema("config map", false),
+		"data": {
+			Type:     schema.TypeMap,
+			Optional: true,
+			Elem:     &schema.Schema{Type: schema.TypeString},
+		},
+	}
+	build := func(d *schema.ResourceDiff) (runtime.Object, error) {
+		if err := DryRunKnown(d, "metadata.0.name", "metadata.0.namespace", "metadata.0.labels", "data"); err != nil {
+			return nil, err
+		}
+		return &api.ConfigMap{
+			ObjectMeta: ExpandMetadata(d.Get("metadata").([]interface{})),
+			Data:       expandStringMap(d.Get("data").(map[string]interface{})),
+		}, nil
+	}
+	return &schema.Resource{
+		Schema: resourceSchema,
+		CustomizeDiff: DryRunCustomizeDiff(func(interface{}) client.Client {
+			return conn
+		}, resourceSchema, build),
+	}, srv.Close
+}
+
+func TestDryRunCustomizeDiff(t *testing.T) {
+	existing := &terraform.InstanceState{
+		ID: "ns/cm",
+		Attributes: map[string]string{
+			"id":                          "ns/cm",
+			"metadata.#":                  "1",
+			"metadata.0.name":             "cm",
+			"metadata.0.namespace":        "ns",
+			"metadata.0.uid":              "uid",
+			"metadata.0.resource_version": "1",
+			"data.%":                      "1",
+			"data.key":                    "old",
+		},
+	}
+	metadata := func(name string) []interface{} {
+		return []interface{}{map[string]interface{}{"name": name, "namespace": "ns"}}
+	}
+	denied := `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"admission webhook \"check.example.com\" denied the request: key is not allowed","reason":"Forbidden","code":403}`
+	rbac := `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"configmaps is forbidden: User \"dev\" cannot create resource \"configmaps\" in API group \"\" in the namespace \"ns\"","reason":"Forbidden","code":403}`
+	webhookCannot := `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"admission webhook \"policy.example.com\" denied the request: containers cannot run as root","reason":"Forbidden","code":403}`
+	exists := `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"configmaps \"cm2\" already exists","reason":"AlreadyExists","code":409}`
+	immutable := `{"kind":"Status","apiVersion":"v1","status":"Failure","message":"ConfigMap \"cm\" is invalid: metadata.name: field is immutable","reason":"Invalid","code":422}`
+
+	cases := []struct {
+		name      string
+		state     *terraform.InstanceState
+		config    map[string]interface{}
+		responses map[string]string
+		requests  []dryRunRequest
+		err       string
+	}{
+		{
+			name:      "create rejected by webhook",
+			config:    map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": "new"}},
+			responses: map[string]string{"POST": denied},
+			requests:  []dryRunRequest{{"POST", "/api/v1/namespaces/ns/configmaps", "All"}},
+			err:       "key is not allowed",
+		},
+		{
+			name:      "create not permitted",
+			config:    map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": "new"}},
+			responses: map[string]string{"POST": rbac},
+			requests:  []dryRunRequest{{"POST", "/api/v1/namespaces/ns/configmaps", "All"}},
+		},
+		{
+			name:      "webhook message mentioning cannot",
+			config:    map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": "new"}},
+			responses: map[string]string{"POST": webhookCannot},
+			requests:  []dryRunRequest{{"POST", "/api/v1/namespaces/ns/configmaps", "All"}},
+			err:       "containers cannot run as root",
+		},
+		{
+			name:      "update without changes",
+			state:     existing,
+			config:    map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": "old"}},
+			responses: map[string]string{"PATCH": immutable},
+		},
+		{
+			name:   "unknown value",
+			config: map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": testUnknownValue}},
+		},
+		{
+			name:      "update",
+			state:     existing,
+			config:    map[string]interface{}{"metadata": metadata("cm"), "data": map[string]interface{}{"key": "new"}},
+			responses: map[string]string{"PATCH": `{"kind":"ConfigMap","apiVersion":"v1","metadata":{"name":"cm","namespace":"ns"}}`},
+			requests:  []dryRunRequest{{"PATCH", "/api/v1/namespaces/ns/configmaps/cm", "All"}},
+		},
+		{
+			name:      "replacement",
+			state:     existing,
+			config:    map[string]interface{}{"metadata": metadata("cm2"), "data": map[string]interface{}{"key": "old"}},
+			responses: map[string]string{"POST": exists, "PATCH": immutable},
+			requests:  []dryRunRequest{{"POST", "/api/v1/namespaces/ns/configmaps", "All"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			var requests []dryRunRequest
+			r, closeServer := newDryRunTestResource(t, c.responses, &requests)
+			defer closeServer()
+			// Terraform plans with SimpleDiff, which keeps prior state of replaced object
+			p := &schema.Provider{
+				ResourcesMap: map[string]*schema.Resource{
+					"test_config_map": r,
+				},
+			}
+			_, err := p.SimpleDiff(&terraform.InstanceInfo{Type: "test_config_map"}, c.state, terraform.NewResourceConfigRaw(c.config))
+			if c.err == "" && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.err != "" && (err == nil || !strings.Contains(err.Error(), c.err)) {
+				t.Fatalf("Expected error containing %q, got %v", c.err, err)
+			}
+			if len(requests) != len(c.requests) {
+				t.Fatalf("Expected requests %v, got %v", c.requests, requests)
+			}
+			for i := range requests {
+				if requests[i] != c.requests[i] {
+					t.Errorf("Expected request %v, got %v", c.requests[i], requests[i])
+				}
+			}
+		})
+	}
+}
@@ -5,17 +5,29 @@ import (
 	"fmt"
 	"log"
 	"sort"
+	"time"
 
 	api "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
-// GetLastWarningsForObject returns last objects for given object metadata
+// eventsPageSize is the number of events requested from API per page
+const eventsPageSize = 100
+
+// GetLastWarningsForObject returns last objects for given object metadata.
+// Warnings are filtered server-side and listed page by page. All pages are read
+// even when limit unique warnings are found early, since API doesn't order events
+// by time and the latest ones may come on the last page.
 func GetLastWarningsForObject(conn client.Client, metadata metav1.ObjectMeta, kind string, limit int) ([]api.Event, error) {
+	if limit <= 0 {
+		return nil, nil
+	}
+
 	selector := client.MatchingFields{
 		"involvedObject.name": metadata.Name,
 		"involvedObject.kind": kind,
+		"type":                api.EventTypeWarning,
 	}
 	if metadata.Namespace != "" {
 		selector["involvedObject.namespace"] = metadata.Namespace
@@ -23,52 +35,95 @@ func GetLastWarningsForObject(conn client.Client, metadata metav1.ObjectMeta, ki
 
 	log.Printf("[DEBUG] Looking up events via this selector: %+v", selector)
 
-	out := api.EventList{}
-	err := conn.List(context.TODO(), &out, selector)
-	if err != nil {
-		return nil, err
-	}
-
-	// It would be better to sort & filter on the server-side
-	// but API doesn't seem to support it
-	var warnings []api.Event
-
-	// Bring latest events to the top, for easy access
-	sort.Slice(out.Items, func(i, j int) bool {
-		return out.Items[i].LastTimestamp.After(out.Items[j].LastTimestamp.Time)
-	})
-
-	log.Printf("[DEBUG] Received %d events for %s/%s (%s)",
-		len(out.Items), metadata.Namespace, metadata.Name, kind)
-
-	warnCount := 0
+	// Server doesn't support sorting, so we keep the latest event
+	// for every unique message and sort what we've collected at the end
 	uniqueWarnings := make(map[string]api.Event, 0)
-	for _, e := range out.Items {
-		if warnCount >= limit {
-			break
+	received := 0
+	continueToken := ""
+	for {
+		out := api.EventList{}
+		opts := []client.ListOption{selector, client.Limit(eventsPageSize)}
+		if continueToken != "" {
+			opts = append(opts, client.Continue(continueToken))
 		}
+		err := conn.List(context.TODO(), &out, opts...)
+		if err != nil {
+			return nil, err
+		}
+		received += len(out.Items)
 
-		if e.Type == api.EventTypeWarning {
-			_, found := uniqueWarnings[e.Message]
-			if found {
+		for _, e := range out.Items {
+			// Field selector should already filter these out,
+			// but not every client (e.g. fake one) respects it
+			if e.Type != api.EventTypeWarning {
+				continue
+			}
+			if found, ok := uniqueWarnings[e.Message]; ok && !EffectiveEventTime(e).After(EffectiveEventTime(found)) {
 				continue
 			}
-			warnings = append(warnings, e)
 			uniqueWarnings[e.Message] = e
-			warnCount++
+		}
+
+		// Pages aren't ordered by time, so all of them are needed to find the latest events
+		continueToken = out.Continue
+		if continueToken == "" {
+			break
 		}
 	}
 
+	log.Printf("[DEBUG] Received %d events for %s/%s (%s)",
+		received, metadata.Namespace, metadata.Name, kind)
+
+	warnings := make([]api.Event, 0, len(uniqueWarnings))
+	for _, e := range uniqueWarnings {
+		warnings = append(warnings, e)
+	}
+
+	// Bring latest events to the top, for easy access
+	sort.Slice(warnings, func(i, j int) bool {
+		return EffectiveEventTime(warnings[i]).After(EffectiveEventTime(warnings[j]))
+	})
+
+	if len(warnings) > limit {
+		warnings = warnings[:limit]
+	}
+
 	return warnings, nil
 }
 
-// StringifyEvents converts events into string
+// EffectiveEventTime returns the time event was last observed.
+// Events created via events.k8s.io API often leave LastTimestamp unset
+// and carry the time in EventTime or Series.LastObservedTime instead.
+func EffectiveEventTime(e api.Event) time.Time {
+	if e.Series != nil && !e.Series.LastObservedTime.IsZero() {
+		return e.Series.LastObservedTime.Time
+	}
+	if !e.LastTimestamp.IsZero() {
+		return e.LastTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	if !e.FirstTimestamp.IsZero() {
+		return e.FirstTimestamp.Time
+	}
+	return e.CreationTimestamp.Time
+}
+
+// StringifyEvents converts events into string, showing the time each event was last observed
 func StringifyEvents(events []api.Event) string {
 	var output string
 	for _, e := range events {
-		output += fmt.Sprintf("\n   * %s (%s): %s: %s",
-			e.InvolvedObject.Name, e.InvolvedObject.Kind,
+		output += fmt.Sprintf("\n   * %s%s (%s): %s: %s",
+			formatEventTime(EffectiveEventTime(e)), e.InvolvedObject.Name, e.InvolvedObject.Kind,
 			e.Reason, e.Message)
 	}
 	return output
 }
+
+func formatEventTime(t time.Time) string {
+	if t.IsZero() {
+		return ""
+	}
+	return "[" + t.UTC().Format(time.RFC3339) + "] "
+}
@@ -0,0 +1,139 @@
+package kubernetes
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestGetLastWarningsForObject(t *testing.T) {
+	base := time.Date(2020, 3, 1, 12, 0, 0, 0, time.UTC)
+	at := func(minutes int) time.Time {
+		return base.Add(time.Duration(minutes) * time.Minute)
+	}
+	event := func(name, message string, set func(e *api.Event)) runtime.Object {
+		e := &api.Event{
+			ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: name, CreationTimestamp: metav1.NewTime(at(0))},
+			InvolvedObject: api.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "pod"},
+			Type:           api.EventTypeWarning,
+			Reason:         "Failed",
+			Message:        message,
+		}
+		set(e)
+		return e
+	}
+
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme,
+		// Legacy event with LastTimestamp
+		event("a", "pull failed", func(e *api.Event) {
+			e.FirstTimestamp = metav1.NewTime(at(1))
+			e.LastTimestamp = metav1.NewTime(at(2))
+		}),
+		// events.k8s.io event with EventTime only
+		event("b", "back-off", func(e *api.Event) {
+			e.EventTime = metav1.NewMicroTime(at(5))
+		}),
+		// Repeated events.k8s.io event, last observed in series
+		event("c", "probe failed", func(e *api.Event) {
+			e.EventTime = metav1.NewMicroTime(at(1))
+			e.Series = &api.EventSeries{Count: 3, LastObservedTime: metav1.NewMicroTime(at(7))}
+		}),
+		// Older duplicate of "back-off"
+		event("d", "back-off", func(e *api.Event) {
+			e.LastTimestamp = metav1.NewTime(at(3))
+		}),
+		// Newer duplicate of "pull failed", set via EventTime
+		event("e", "pull failed", func(e *api.Event) {
+			e.EventTime = metav1.NewMicroTime(at(4))
+		}),
+		// Normal events are ignored
+		event("f", "started", func(e *api.Event) {
+			e.Type = api.EventTypeNormal
+			e.LastTimestamp = metav1.NewTime(at(9))
+		}),
+	)
+	metadata := metav1.ObjectMeta{Namespace: "ns", Name: "pod"}
+
+	cases := []struct {
+		limit    int
+		expected []string
+	}{
+		{limit: 5, expected: []string{"c", "b", "e"}},
+		{limit: 2, expected: []string{"c", "b"}},
+		{limit: 0, expected: []string{}},
+	}
+	for _, c := range cases {
+		warnings, err := GetLastWarningsForObject(conn, metadata, "Pod", c.limit)
+		if err != nil {
+			t.Fatal(err)
+		}
+		names := make([]string, 0, len(warnings))
+		for _, w := range warnings {
+			names = append(names, w.Name)
+		}
+		if strings.Join(names, ",") != strings.Join(c.expected, ",") {
+			t.Errorf("Limit %d: expected events %v, got %v", c.limit, c.expected, names)
+		}
+	}
+
+	warnings, err := GetLastWarningsForObject(conn, metadata, "Pod", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := "\n   * [2020-03-01T12:07:00Z] pod (Pod): Failed: probe failed"
+	if output := StringifyEvents(warnings); output != expected {
+		t.Errorf("Expected %q, got %q", expected, output)
+	}
+}
+
+func TestGetLastWarningsForObjectReadsAllPages(t *testing.T) {
+	// Latest warning comes on the second page, after the first one had enough warnings
+	pages := map[string]string{
+		"": `{"kind": "EventList", "apiVersion": "v1", "metadata": {"continue": "page-2"}, "items": [
+			{"metadata": {"namespace": "ns", "name": "a"}, "type": "Warning", "message": "pull failed", "lastTimestamp": "2020-03-01T12:01:00Z"}
+		]}`,
+		"page-2": `{"kind": "EventList", "apiVersion": "v1", "metadata": {}, "items": [
+			{"metadata": {"namespace": "ns", "name": "b"}, "type": "Warning", "message": "back-off", "lastTimestamp": "2020-03-01T12:05:00Z"}
+		]}`,
+	}
+	var requests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		requests = append(requests, query.Get("limit")+" "+query.Get("continue"))
+		if !strings.Contains(query.Get("fieldSelector"), "type=Warning") {
+			t.Errorf("Warnings aren't filtered server-side: %s", query.Get("fieldSelector"))
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(pages[query.Get("continue")]))
+	}))
+	defer srv.Close()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(api.SchemeGroupVersion.WithKind("Event"), meta.RESTScopeNamespace)
+	conn, err := client.New(&rest.Config{Host: srv.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	warnings, err := GetLastWarningsForObject(conn, metav1.ObjectMeta{Namespace: "ns", Name: "pod"}, "Pod", 1)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(warnings) != 1 || warnings[0].Name != "b" {
+		t.Errorf("Expected the latest warning b, got %v", warnings)
+	}
+	if strings.Join(requests, ",") != "100 ,100 page-2" {
+		t.Errorf("Expected both pages to be requested, got %q", requests)
+	}
+}
@@ -0,0 +1,122 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	api "k8s.io/api/core/v1"
+)
+
+// DiagnosticSeverity represents severity of a diagnostic
+type DiagnosticSeverity string
+
+const (
+	// DiagnosticError is a diagnostic which should fail the operation
+	DiagnosticError DiagnosticSeverity = "error"
+	// DiagnosticWarning is a diagnostic which is only displayed to the user
+	DiagnosticWarning DiagnosticSeverity = "warning"
+)
+
+// Diagnostic is a summary + detail pair in the shape Terraform SDK diagnostics use
+type Diagnostic struct {
+	Severity DiagnosticSeverity `json:"severity"`
+	Summary  string             `json:"summary"`
+	Detail   string             `json:"detail"`
+}
+
+// EventObject identifies object the event is about
+type EventObject struct {
+	Kind      string `json:"kind"`
+	Namespace string `json:"namespace,omitempty"`
+	Name      string `json:"name"`
+}
+
+func (o EventObject) String() string {
+	if o.Namespace == "" {
+		return fmt.Sprintf("%s (%s)", o.Name, o.Kind)
+	}
+	return fmt.Sprintf("%s/%s (%s)", o.Namespace, o.Name, o.Kind)
+}
+
+// EventWarning is structured representation of a warning event
+type EventWarning struct {
+	Object    EventObject `json:"object"`
+	Reason    string      `json:"reason"`
+	Message   string      `json:"message"`
+	Count     int32       `json:"count"`
+	FirstSeen time.Time   `json:"first_seen"`
+	LastSeen  time.Time   `json:"last_seen"`
+}
+
+// Diagnostic converts warning into diagnostic
+func (w EventWarning) Diagnostic() Diagnostic {
+	return Diagnostic{
+		Severity: DiagnosticWarning,
+		Summary:  fmt.Sprintf("%s: %s", w.Object, w.Reason),
+		Detail: fmt.Sprintf("%s (seen %d times, first at %s, last at %s)",
+			w.Message, w.Count,
+			w.FirstSeen.Format(time.RFC3339), w.LastSeen.Format(time.RFC3339)),
+	}
+}
+
+// EventWarnings is list of structured warning events
+type EventWarnings []EventWarning
+
+// NewEventWarnings converts events, e.g. from GetLastWarningsForObject, into structured warnings
+func NewEventWarnings(events []api.Event) EventWarnings {
+	warnings := make(EventWarnings, 0, len(events))
+	for _, e := range events {
+		warnings = append(warnings, EventWarning{
+			Object: EventObject{
+				Kind:      e.InvolvedObject.Kind,
+				Namespace: e.InvolvedObject.Namespace,
+				Name:      e.InvolvedObject.Name,
+			},
+			Reason:    e.Reason,
+			Message:   e.Message,
+			Count:     eventCount(e),
+			FirstSeen: eventFirstSeen(e),
+			LastSeen:  EffectiveEventTime(e),
+		})
+	}
+	return warnings
+}
+
+// MarshalJSON marshals warnings to json
+func (ws EventWarnings) MarshalJSON() ([]byte, error) {
+	var v []EventWarning = ws
+	if v == nil {
+		v = []EventWarning{}
+	}
+	return json.Marshal(v)
+}
+
+// Diagnostics converts warnings into list of diagnostics
+func (ws EventWarnings) Diagnostics() []Diagnostic {
+	diags := make([]Diagnostic, 0, len(ws))
+	for _, w := range ws {
+		diags = append(diags, w.Diagnostic())
+	}
+	return diags
+}
+
+func eventCount(e api.Event) int32 {
+	if e.Series != nil && e.Series.Count > 0 {
+		return e.Series.Count
+	}
+	if e.Count > 0 {
+		return e.Count
+	}
+	return 1
+}
+
+func eventFirstSeen(e api.Event) time.Time {
+	if !e.FirstTimestamp.IsZero() {
+		return e.FirstTimestamp.Time
+	}
+	if !e.EventTime.IsZero() {
+		return e.EventTime.Time
+	}
+	return EffectiveEventTime(e)
+}
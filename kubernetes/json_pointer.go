@@ -0,0 +1,136 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strings"
+)
+
+// JSONPointer is parsed JSON pointer per RFC 6901,
+// list of unescaped reference tokens. Empty pointer references whole document.
+type JSONPointer []string
+
+// NewJSONPointer builds pointer from unescaped reference tokens
+func NewJSONPointer(tokens ...string) JSONPointer {
+	return append(JSONPointer{}, tokens...)
+}
+
+// ParseJSONPointer parses string representation of JSON pointer
+func ParseJSONPointer(s string) (JSONPointer, error) {
+	if s == "" {
+		return JSONPointer{}, nil
+	}
+	if !strings.HasPrefix(s, "/") {
+		return nil, fmt.Errorf("JSON pointer %q must start with /", s)
+	}
+
+	parts := strings.Split(s[1:], "/")
+	pointer := make(JSONPointer, 0, len(parts))
+	for _, p := range parts {
+		token, err := UnescapeJSONPointerToken(p)
+		if err != nil {
+			return nil, fmt.Errorf("JSON pointer %q is invalid: %s", s, err)
+		}
+		pointer = append(pointer, token)
+	}
+	return pointer, nil
+}
+
+// Append returns new pointer extended by given unescaped reference tokens
+func (p JSONPointer) Append(tokens ...string) JSONPointer {
+	out := make(JSONPointer, 0, len(p)+len(tokens))
+	out = append(out, p...)
+	return append(out, tokens...)
+}
+
+// IsRoot tells if pointer references whole document
+func (p JSONPointer) IsRoot() bool {
+	return len(p) == 0
+}
+
+// Parent returns pointer without the last token
+func (p JSONPointer) Parent() JSONPointer {
+	if len(p) == 0 {
+		return p
+	}
+	return NewJSONPointer(p[:len(p)-1]...)
+}
+
+// Last returns last unescaped reference token
+func (p JSONPointer) Last() string {
+	if len(p) == 0 {
+		return ""
+	}
+	return p[len(p)-1]
+}
+
+// HasPrefix tells if pointer is equal to or references location inside of prefix
+func (p JSONPointer) HasPrefix(prefix JSONPointer) bool {
+	if len(prefix) > len(p) {
+		return false
+	}
+	for i := range prefix {
+		if p[i] != prefix[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// Matches tells if pointer matches the pattern, where * token matches any token
+func (p JSONPointer) Matches(pattern JSONPointer) bool {
+	if len(p) != len(pattern) {
+		return false
+	}
+	for i := range pattern {
+		if pattern[i] != "*" && pattern[i] != p[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func (p JSONPointer) String() string {
+	var b strings.Builder
+	for _, token := range p {
+		b.WriteString("/")
+		b.WriteString(EscapeJSONPointerToken(token))
+	}
+	return b.String()
+}
+
+// EscapeJSONPointerToken escapes string per RFC 6901
+// so it can be used as path segment in JSON patch operations
+func EscapeJSONPointerToken(token string) string {
+	token = strings.Replace(token, "~", "~0", -1)
+	token = strings.Replace(token, "/", "~1", -1)
+	return token
+}
+
+// UnescapeJSONPointerToken reverts EscapeJSONPointerToken
+func UnescapeJSONPointerToken(token string) (string, error) {
+	for i := 0; i < len(token); i++ {
+		if token[i] != '~' {
+			continue
+		}
+		if i+1 >= len(token) || (token[i+1] != '0' && token[i+1] != '1') {
+			return "", fmt.Errorf("invalid escape sequence in %q", token)
+		}
+	}
+	token = strings.Replace(token, "~1", "/", -1)
+	token = strings.Replace(token, "~0", "~", -1)
+	return token, nil
+}
+
+// parseJSONPointerPrefix parses path prefix passed to patch helpers,
+// tolerating missing leading and trailing slashes
+func parseJSONPointerPrefix(prefix string) JSONPointer {
+	prefix = strings.Trim(prefix, "/")
+	if prefix == "" {
+		return JSONPointer{}
+	}
+	pointer, err := ParseJSONPointer("/" + prefix)
+	if err != nil {
+		return NewJSONPointer(strings.Split(prefix, "/")...)
+	}
+	return pointer
+}
@@ -0,0 +1,86 @@
+package kubernetes
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseJSONPointer(t *testing.T) {
+	cases := []struct {
+		pointer string
+		tokens  JSONPointer
+		invalid bool
+	}{
+		{pointer: "", tokens: JSONPointer{}},
+		{pointer: "/", tokens: JSONPointer{""}},
+		{pointer: "/foo", tokens: JSONPointer{"foo"}},
+		{pointer: "/foo/0", tokens: JSONPointer{"foo", "0"}},
+		{pointer: "//", tokens: JSONPointer{"", ""}},
+		{pointer: "/a//b/", tokens: JSONPointer{"a", "", "b", ""}},
+		{pointer: "/a~1b", tokens: JSONPointer{"a/b"}},
+		{pointer: "/m~0n", tokens: JSONPointer{"m~n"}},
+		{pointer: "/~01", tokens: JSONPointer{"~1"}},
+		{pointer: "/~10", tokens: JSONPointer{"/0"}},
+		{pointer: "/metadata/labels/app.kubernetes.io~1name", tokens: JSONPointer{"metadata", "labels", "app.kubernetes.io/name"}},
+		{pointer: "foo", invalid: true},
+		{pointer: "/~2", invalid: true},
+		{pointer: "/a~", invalid: true},
+		{pointer: "/~a", invalid: true},
+	}
+
+	for _, c := range cases {
+		pointer, err := ParseJSONPointer(c.pointer)
+		if c.invalid {
+			if err == nil {
+				t.Errorf("%q: expected error, got %q", c.pointer, pointer)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.pointer, err)
+			continue
+		}
+		if !reflect.DeepEqual(pointer, c.tokens) {
+			t.Errorf("%q: expected tokens %q, got %q", c.pointer, c.tokens, pointer)
+		}
+		if s := pointer.String(); s != c.pointer {
+			t.Errorf("%q: round trip produced %q", c.pointer, s)
+		}
+	}
+}
+
+func TestJSONPointerTokenEscaping(t *testing.T) {
+	cases := []struct {
+		token   string
+		escaped string
+	}{
+		{token: "", escaped: ""},
+		{token: "plain", escaped: "plain"},
+		{token: "~", escaped: "~0"},
+		{token: "/", escaped: "~1"},
+		{token: "~1", escaped: "~01"},
+		{token: "/0", escaped: "~10"},
+		{token: "a/b~c", escaped: "a~1b~0c"},
+		{token: "~~//", escaped: "~0~0~1~1"},
+	}
+
+	for _, c := range cases {
+		if escaped := EscapeJSONPointerToken(c.token); escaped != c.escaped {
+			t.Errorf("%q: expected escaped %q, got %q", c.token, c.escaped, escaped)
+		}
+		token, err := UnescapeJSONPointerToken(c.escaped)
+		if err != nil {
+			t.Errorf("%q: unexpected error: %s", c.escaped, err)
+			continue
+		}
+		if token != c.token {
+			t.Errorf("%q: expected unescaped %q, got %q", c.escaped, c.token, token)
+		}
+	}
+
+	for _, invalid := range []string{"~", "~2", "a~b", "~~"} {
+		if token, err := UnescapeJSONPointerToken(invalid); err == nil {
+			t.Errorf("%q: expected error, got %q", invalid, token)
+		}
+	}
+}
@@ -0,0 +1,59 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ResolveOwnerReferences sets missing UIDs of owner references by looking up referents by name.
+// Referents are looked up in given namespace, unless they are cluster-scoped.
+func ResolveOwnerReferences(ctx context.Context, conn client.Client, namespace string, refs []metav1.OwnerReference) error {
+	for i := range refs {
+		if refs[i].UID != "" {
+			continue
+		}
+		owner := &unstructured.Unstructured{}
+		owner.SetAPIVersion(refs[i].APIVersion)
+		owner.SetKind(refs[i].Kind)
+		key := client.ObjectKey{Namespace: namespace, Name: refs[i].Name}
+		if err := conn.Get(ctx, key, owner); err != nil {
+			return fmt.Errorf("Failed to resolve UID of owner %s %s: %s", refs[i].Kind, key, err)
+		}
+		refs[i].UID = owner.GetUID()
+	}
+	return nil
+}
+
+// ResolvePatchOwnerReferences sets missing UIDs of owner references added or replaced by patch operations
+func ResolvePatchOwnerReferences(ctx context.Context, conn client.Client, namespace string, ops PatchOperations) error {
+	for _, op := range ops {
+		if !strings.Contains(op.GetPath(), "/ownerReferences") {
+			continue
+		}
+		var value interface{}
+		switch o := op.(type) {
+		case *AddOperation:
+			value = o.Value
+		case *ReplaceOperation:
+			value = o.Value
+		}
+		switch v := value.(type) {
+		case []metav1.OwnerReference:
+			if err := ResolveOwnerReferences(ctx, conn, namespace, v); err != nil {
+				return err
+			}
+		case *metav1.OwnerReference:
+			refs := []metav1.OwnerReference{*v}
+			if err := ResolveOwnerReferences(ctx, conn, namespace, refs); err != nil {
+				return err
+			}
+			*v = refs[0]
+		}
+	}
+	return nil
+}
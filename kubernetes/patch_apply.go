@@ -0,0 +1,290 @@
+package kubernetes
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// Apply applies operations to JSON document per RFC 6902,
+// so the result of the patch can be checked without sending it to API
+func (po PatchOperations) Apply(doc []byte) ([]byte, error) {
+	v, err := decodeJSON(doc)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to parse document: %s", err)
+	}
+
+	for i, op := range po {
+		v, err = applyOperation(v, op)
+		if err != nil {
+			return nil, fmt.Errorf("Failed to apply operation #%d (%v): %s", i, op, err)
+		}
+	}
+
+	return json.Marshal(v)
+}
+
+func applyOperation(doc interface{}, op PatchOperation) (interface{}, error) {
+	path, err := ParseJSONPointer(op.GetPath())
+	if err != nil {
+		return nil, err
+	}
+
+	switch o := op.(type) {
+	case *AddOperation:
+		value, err := normalizeJSONValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, value)
+	case *ReplaceOperation:
+		value, err := normalizeJSONValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		if _, err := jsonGet(doc, path); err != nil {
+			return nil, err
+		}
+		return jsonSet(doc, path, value)
+	case *RemoveOperation:
+		return jsonRemove(doc, path)
+	case *TestOperation:
+		expected, err := normalizeJSONValue(o.Value)
+		if err != nil {
+			return nil, err
+		}
+		actual, err := jsonGet(doc, path)
+		if err != nil {
+			return nil, err
+		}
+		if !jsonEqual(actual, expected) {
+			return nil, fmt.Errorf("test failed: value at %s is %s", path, stringifyJSONValue(actual))
+		}
+		return doc, nil
+	case *MoveOperation:
+		from, err := ParseJSONPointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		if len(path) > len(from) && path.HasPrefix(from) {
+			return nil, fmt.Errorf("cannot move %s into its own child %s", from, path)
+		}
+		value, err := jsonGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		doc, err = jsonRemove(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, value)
+	case *CopyOperation:
+		from, err := ParseJSONPointer(o.From)
+		if err != nil {
+			return nil, err
+		}
+		value, err := jsonGet(doc, from)
+		if err != nil {
+			return nil, err
+		}
+		value, err = normalizeJSONValue(value)
+		if err != nil {
+			return nil, err
+		}
+		return jsonAdd(doc, path, value)
+	}
+
+	return nil, fmt.Errorf("unsupported operation %T", op)
+}
+
+// jsonGet returns value at the path
+func jsonGet(doc interface{}, path JSONPointer) (interface{}, error) {
+	current := doc
+	for i, token := range path {
+		switch c := current.(type) {
+		case map[string]interface{}:
+			v, ok := c[token]
+			if !ok {
+				return nil, fmt.Errorf("path %s does not exist", path[:i+1])
+			}
+			current = v
+		case []interface{}:
+			idx, err := parseArrayIndex(token, len(c)-1)
+			if err != nil {
+				return nil, fmt.Errorf("path %s does not exist: %s", path[:i+1], err)
+			}
+			current = c[idx]
+		default:
+			return nil, fmt.Errorf("path %s does not exist: %s is not an object or array", path[:i+1], path[:i])
+		}
+	}
+	return current, nil
+}
+
+// jsonSet sets value at the existing path, returning updated document
+func jsonSet(doc interface{}, path JSONPointer, value interface{}) (interface{}, error) {
+	if path.IsRoot() {
+		return value, nil
+	}
+	parent, err := jsonGet(doc, path.Parent())
+	if err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[path.Last()] = value
+	case []interface{}:
+		idx, err := parseArrayIndex(path.Last(), len(p)-1)
+		if err != nil {
+			return nil, fmt.Errorf("path %s does not exist: %s", path, err)
+		}
+		p[idx] = value
+	default:
+		return nil, fmt.Errorf("path %s is not an object or array", path.Parent())
+	}
+	return doc, nil
+}
+
+// jsonAdd adds value at the path per RFC 6902 add semantics, returning updated document
+func jsonAdd(doc interface{}, path JSONPointer, value interface{}) (interface{}, error) {
+	if path.IsRoot() {
+		return value, nil
+	}
+	parent, err := jsonGet(doc, path.Parent())
+	if err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		p[path.Last()] = value
+		return doc, nil
+	case []interface{}:
+		idx := len(p)
+		if path.Last() != "-" {
+			idx, err = parseArrayIndex(path.Last(), len(p))
+			if err != nil {
+				return nil, fmt.Errorf("cannot add to %s: %s", path, err)
+			}
+		}
+		updated := make([]interface{}, 0, len(p)+1)
+		updated = append(updated, p[:idx]...)
+		updated = append(updated, value)
+		updated = append(updated, p[idx:]...)
+		return jsonSet(doc, path.Parent(), updated)
+	}
+	return nil, fmt.Errorf("cannot add to %s: %s is not an object or array", path, path.Parent())
+}
+
+// jsonRemove removes value at the path, returning updated document
+func jsonRemove(doc interface{}, path JSONPointer) (interface{}, error) {
+	if path.IsRoot() {
+		return nil, fmt.Errorf("cannot remove whole document")
+	}
+	parent, err := jsonGet(doc, path.Parent())
+	if err != nil {
+		return nil, err
+	}
+	switch p := parent.(type) {
+	case map[string]interface{}:
+		if _, ok := p[path.Last()]; !ok {
+			return nil, fmt.Errorf("path %s does not exist", path)
+		}
+		delete(p, path.Last())
+		return doc, nil
+	case []interface{}:
+		idx, err := parseArrayIndex(path.Last(), len(p)-1)
+		if err != nil {
+			return nil, fmt.Errorf("path %s does not exist: %s", path, err)
+		}
+		updated := make([]interface{}, 0, len(p)-1)
+		updated = append(updated, p[:idx]...)
+		updated = append(updated, p[idx+1:]...)
+		return jsonSet(doc, path.Parent(), updated)
+	}
+	return nil, fmt.Errorf("path %s does not exist: %s is not an object or array", path, path.Parent())
+}
+
+// parseArrayIndex parses array index per RFC 6901, index has to be in [0, max]
+func parseArrayIndex(token string, max int) (int, error) {
+	if token == "" || (len(token) > 1 && token[0] == '0') {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	idx, err := strconv.Atoi(token)
+	if err != nil || idx < 0 {
+		return 0, fmt.Errorf("invalid array index %q", token)
+	}
+	if idx > max {
+		return 0, fmt.Errorf("array index %d is out of bounds", idx)
+	}
+	return idx, nil
+}
+
+func decodeJSON(data []byte) (interface{}, error) {
+	var v interface{}
+	d := json.NewDecoder(bytes.NewReader(data))
+	d.UseNumber()
+	if err := d.Decode(&v); err != nil {
+		return nil, err
+	}
+	return v, nil
+}
+
+// normalizeJSONValue converts Go value into deep copy made of JSON types
+func normalizeJSONValue(v interface{}) (interface{}, error) {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return nil, err
+	}
+	return decodeJSON(b)
+}
+
+// jsonEqual compares normalized JSON values, treating numbers by their value
+func jsonEqual(a, b interface{}) bool {
+	switch av := a.(type) {
+	case json.Number:
+		bv, ok := b.(json.Number)
+		if !ok {
+			return false
+		}
+		af, aErr := av.Float64()
+		bf, bErr := bv.Float64()
+		if aErr != nil || bErr != nil {
+			return av == bv
+		}
+		return af == bf
+	case map[string]interface{}:
+		bv, ok := b.(map[string]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for k, v := range av {
+			other, ok := bv[k]
+			if !ok || !jsonEqual(v, other) {
+				return false
+			}
+		}
+		return true
+	case []interface{}:
+		bv, ok := b.([]interface{})
+		if !ok || len(av) != len(bv) {
+			return false
+		}
+		for i := range av {
+			if !jsonEqual(av[i], bv[i]) {
+				return false
+			}
+		}
+		return true
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+func stringifyJSONValue(v interface{}) string {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Sprintf("%v", v)
+	}
+	return string(b)
+}
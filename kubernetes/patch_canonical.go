@@ -0,0 +1,238 @@
+package kubernetes
+
+import (
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+)
+
+// canonicalRank orders operation kinds in canonical order
+var canonicalRank = map[string]int{
+	"test":    0,
+	"remove":  1,
+	"replace": 2,
+	"add":     3,
+	"move":    4,
+	"copy":    5,
+}
+
+// canonicalEntry is operation with data needed for canonical ordering
+type canonicalEntry struct {
+	op   PatchOperation
+	name string
+	path JSONPointer
+	from JSONPointer
+	json string
+	// index is position in original operations
+	index int
+	// dependent operations have effect depending on preceding operations
+	dependent bool
+}
+
+// Canonical returns copy of operations in canonical order: tests, removes, replaces, adds,
+// moves and copies, each ordered by path. Duplicate operations are dropped. Error is returned
+// for conflicting operations, i.e. different values set at the same path or operations
+// on overlapping paths. Canonical order assumes operations don't depend on each other,
+// like the ones produced by PatchMetadata for maps.
+//
+// Operations depending on array indices, i.e. moves, copies and operations on array items,
+// and tests of values changed by preceding operations are kept in their original order
+// after all other operations, as reordering them would change what they do. Error is returned when other operation overlapping with them
+// originally follows them.
+func (po PatchOperations) Canonical() (PatchOperations, error) {
+	entries := canonicalEntries(po)
+
+	out := make([]PatchOperation, 0, len(entries))
+	var kept []canonicalEntry
+	for i, e := range entries {
+		if e.dependent {
+			for _, k := range kept {
+				if err := checkDependentConflict(k, e); err != nil {
+					return nil, err
+				}
+			}
+			out = append(out, e.op)
+			continue
+		}
+		if i > 0 && e.json == entries[i-1].json {
+			continue
+		}
+		for _, k := range kept {
+			if err := checkConflict(k, e); err != nil {
+				return nil, err
+			}
+		}
+		kept = append(kept, e)
+		out = append(out, e.op)
+	}
+	return out, nil
+}
+
+// DiffPatches describes differences between expected and actual operations,
+// to be used in test failure messages. Empty string means operations are equal.
+func DiffPatches(expected, actual PatchOperations) string {
+	a := canonicalEntries(expected)
+	b := canonicalEntries(actual)
+
+	var lines []string
+	i, j := 0, 0
+	for i < len(a) || j < len(b) {
+		switch {
+		// Dependent operations are compared by position
+		case i < len(a) && j < len(b) && a[i].dependent && b[j].dependent:
+			if a[i].json != b[j].json {
+				lines = append(lines, "- "+a[i].json, "+ "+b[j].json)
+			}
+			i++
+			j++
+		case j >= len(b) || (i < len(a) && canonicalLess(a[i], b[j])):
+			lines = append(lines, "- "+a[i].json)
+			i++
+		case i >= len(a) || canonicalLess(b[j], a[i]):
+			lines = append(lines, "+ "+b[j].json)
+			j++
+		default:
+			i++
+			j++
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// canonicalEntries returns sorted entries for operations, leaving the input intact.
+// Dependent operations go last in their original order.
+func canonicalEntries(ops []PatchOperation) []canonicalEntry {
+	entries := make([]canonicalEntry, 0, len(ops))
+	for i, op := range ops {
+		e := canonicalEntry{op: op, index: i}
+		b, err := op.MarshalJSON()
+		if err == nil {
+			e.json = string(b)
+		}
+		e.name = operationName(op)
+		e.path = parseOperationPath(op.GetPath())
+		switch o := op.(type) {
+		case *MoveOperation:
+			e.from = parseOperationPath(o.From)
+		case *CopyOperation:
+			e.from = parseOperationPath(o.From)
+		}
+		e.dependent = e.from != nil || hasArrayIndex(e.path) ||
+			(e.name == "test" && followsOverlappingChange(e, entries))
+		entries = append(entries, e)
+	}
+	sort.SliceStable(entries, func(i, j int) bool {
+		return canonicalLess(entries[i], entries[j])
+	})
+	return entries
+}
+
+// canonicalLess orders independent operations, dependent ones are all equal and go last
+func canonicalLess(a, b canonicalEntry) bool {
+	if a.dependent || b.dependent {
+		return !a.dependent && b.dependent
+	}
+	if canonicalRank[a.name] != canonicalRank[b.name] {
+		return canonicalRank[a.name] < canonicalRank[b.name]
+	}
+	if a.op.GetPath() != b.op.GetPath() {
+		return a.op.GetPath() < b.op.GetPath()
+	}
+	return a.json < b.json
+}
+
+func parseOperationPath(path string) JSONPointer {
+	pointer, err := ParseJSONPointer(path)
+	if err != nil {
+		return NewJSONPointer(path)
+	}
+	return pointer
+}
+
+// hasArrayIndex tells if pointer may reference an array item.
+// Numeric object keys can't be told apart from indices without the document.
+func hasArrayIndex(path JSONPointer) bool {
+	for _, token := range path {
+		if token == "-" {
+			return true
+		}
+		if _, err := parseArrayIndex(token, math.MaxInt32); err == nil {
+			return true
+		}
+	}
+	return false
+}
+
+// followsOverlappingChange tells if test checks a value changed by preceding operation,
+// so it can't be moved in front of it
+func followsOverlappingChange(test canonicalEntry, preceding []canonicalEntry) bool {
+	for _, p := range preceding {
+		if p.name == "test" {
+			continue
+		}
+		for _, path := range []JSONPointer{p.path, p.from} {
+			if path != nil && (test.path.HasPrefix(path) || path.HasPrefix(test.path)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// checkDependentConflict returns error if independent operation following dependent one
+// overlaps with it, so moving it in front of the dependent one may change the result
+func checkDependentConflict(independent, dependent canonicalEntry) error {
+	if independent.index < dependent.index {
+		return nil
+	}
+	for _, p := range []JSONPointer{dependent.path, dependent.from} {
+		if p == nil {
+			continue
+		}
+		if independent.path.HasPrefix(p) || p.HasPrefix(independent.path) {
+			return fmt.Errorf("Operation %s overlaps with array operation %s and can't be reordered",
+				independent.json, dependent.json)
+		}
+	}
+	return nil
+}
+
+// checkConflict returns error if operations can't be applied in arbitrary order
+func checkConflict(a, b canonicalEntry) error {
+	if a.name == "test" || b.name == "test" {
+		return nil
+	}
+
+	if a.path.String() == b.path.String() {
+		// Remove followed by add is a replace
+		if a.name == "remove" && b.name != "remove" {
+			return nil
+		}
+		return fmt.Errorf("Conflicting operations on %s: %s and %s", a.path, a.json, b.json)
+	}
+
+	if a.path.HasPrefix(b.path) || b.path.HasPrefix(a.path) {
+		return fmt.Errorf("Operations on overlapping paths %s and %s: %s and %s",
+			a.path, b.path, a.json, b.json)
+	}
+	return nil
+}
+
+func operationName(op PatchOperation) string {
+	switch op.(type) {
+	case *TestOperation:
+		return "test"
+	case *RemoveOperation:
+		return "remove"
+	case *ReplaceOperation:
+		return "replace"
+	case *AddOperation:
+		return "add"
+	case *MoveOperation:
+		return "move"
+	case *CopyOperation:
+		return "copy"
+	}
+	return ""
+}
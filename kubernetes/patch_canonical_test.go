@@ -0,0 +1,92 @@
+package kubernetes
+
+import (
+	"testing"
+)
+
+func TestCanonicalKeepsArrayOperationsInOrder(t *testing.T) {
+	doc := `{"labels":{"b":"1","a":"1"},"l":[{"k":"x","v":1},{"k":"y","v":1},{"k":"z","v":1}]}`
+	oldV, err := decodeJSON([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	newV, err := decodeJSON([]byte(`{"labels":{"a":"2","c":"1"},"l":[{"k":"x","v":1},{"k":"z","v":2},{"k":"y","v":1}]}`))
+	if err != nil {
+		t.Fatal(err)
+	}
+	ops := Diff("", oldV, newV, DiffOptions{ListDiffOptions: ListDiffOptions{Strategy: ListByMergeKey, MergeKey: "k"}})
+
+	canonical, err := ops.Canonical()
+	if err != nil {
+		t.Fatalf("Failed to canonicalize %v: %s", ops, err)
+	}
+	expected, err := ops.Apply([]byte(doc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	actual, err := canonical.Apply([]byte(doc))
+	if err != nil {
+		t.Fatalf("Failed to apply canonical operations %v: %s", canonical, err)
+	}
+	if string(expected) != string(actual) {
+		t.Errorf("Canonical operations changed the result:\n%s\n%s", expected, actual)
+	}
+	if diff := DiffPatches(ops, canonical); diff != "" {
+		t.Errorf("Expected canonical operations to be equal to original ones:\n%s", diff)
+	}
+
+	swapped := PatchOperations{
+		&MoveOperation{From: "/l/2", Path: "/l/1"},
+		&ReplaceOperation{Path: "/l/1/v", Value: 2},
+	}
+	reordered := PatchOperations{swapped[1], swapped[0]}
+	if swapped.Equal(reordered) {
+		t.Errorf("Expected reordered array operations to differ")
+	}
+}
+
+func TestCanonicalRejectsOverlapWithPrecedingArrayOperation(t *testing.T) {
+	ops := PatchOperations{
+		&RemoveOperation{Path: "/l/0"},
+		&ReplaceOperation{Path: "/l", Value: []interface{}{}},
+	}
+	if _, err := ops.Canonical(); err == nil {
+		t.Error("Expected error for replace of list following removal of its item")
+	}
+
+	ops = PatchOperations{
+		&TestOperation{Path: "/metadata/finalizers", Value: []interface{}{"a"}},
+		&RemoveOperation{Path: "/metadata/finalizers/0"},
+	}
+	if _, err := ops.Canonical(); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+}
+
+func TestCanonicalKeepsTestsOfChangedValuesInPlace(t *testing.T) {
+	doc := []byte(`{"a":1,"b":1}`)
+	ops := PatchOperations{
+		&ReplaceOperation{Path: "/a", Value: 2},
+		&TestOperation{Path: "/a", Value: 2},
+		&TestOperation{Path: "/b", Value: 1},
+	}
+	if _, err := ops.Apply(doc); err != nil {
+		t.Fatal(err)
+	}
+
+	canonical, err := ops.Canonical()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := canonical.Apply(doc); err != nil {
+		t.Errorf("Failed to apply canonical operations %v: %s", canonical, err)
+	}
+	if _, ok := canonical[0].(*TestOperation); !ok || canonical[0].GetPath() != "/b" {
+		t.Errorf("Expected independent test to go first, got %v", canonical)
+	}
+
+	reordered := PatchOperations{ops[1], ops[0], ops[2]}
+	if ops.Equal(reordered) {
+		t.Error("Expected test moved in front of replace to differ")
+	}
+}
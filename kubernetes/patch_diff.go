@@ -0,0 +1,250 @@
+package kubernetes
+
+import (
+	"fmt"
+	"reflect"
+	"strconv"
+)
+
+// ListStrategy defines how lists are compared by Diff
+type ListStrategy int
+
+const (
+	// ListReplace replaces whole list when it differs
+	ListReplace ListStrategy = iota
+	// ListByIndex compares list items index-wise
+	ListByIndex
+	// ListByMergeKey matches list items by value of the merge key, e.g. name
+	ListByMergeKey
+)
+
+// ListDiffOptions configures how lists are compared
+type ListDiffOptions struct {
+	Strategy ListStrategy
+	// MergeKey is the key items are matched by with ListByMergeKey strategy
+	MergeKey string
+}
+
+// DiffOptions customizes Diff
+type DiffOptions struct {
+	// ListDiffOptions are used for lists which aren't listed in Lists
+	ListDiffOptions
+	// Lists overrides options for lists at given paths, where * matches any
+	// path segment, e.g. /spec/containers or /spec/containers/*/env
+	Lists map[string]ListDiffOptions
+}
+
+// Diff compares two JSON-compatible trees of maps, lists and scalars and produces
+// patch operations turning old value into new one. Only keys present in old or new value
+// are touched, so items managed outside of TF are left intact.
+func Diff(pathPrefix string, oldV, newV interface{}, opts DiffOptions) PatchOperations {
+	d := &differ{opts: opts}
+	d.diff(parseJSONPointerPrefix(pathPrefix), oldV, newV)
+	if d.ops == nil {
+		return make([]PatchOperation, 0, 0)
+	}
+	return d.ops
+}
+
+type differ struct {
+	opts DiffOptions
+	ops  []PatchOperation
+}
+
+func (d *differ) diff(path JSONPointer, oldV, newV interface{}) {
+	switch n := newV.(type) {
+	case map[string]interface{}:
+		if o, ok := oldV.(map[string]interface{}); ok {
+			d.diffMap(path, o, n)
+			return
+		}
+	case []interface{}:
+		if o, ok := oldV.([]interface{}); ok {
+			d.diffList(path, o, n)
+			return
+		}
+	}
+
+	if reflect.DeepEqual(oldV, newV) {
+		return
+	}
+	d.ops = append(d.ops, &ReplaceOperation{
+		Path:  path.String(),
+		Value: newV,
+	})
+}
+
+func (d *differ) diffMap(path JSONPointer, oldV, newV map[string]interface{}) {
+	// If old value was empty, just create the object
+	if len(oldV) == 0 {
+		if len(newV) > 0 {
+			d.ops = append(d.ops, &AddOperation{
+				Path:  path.String(),
+				Value: newV,
+			})
+		}
+		return
+	}
+
+	for k := range oldV {
+		if _, ok := newV[k]; ok {
+			continue
+		}
+		d.ops = append(d.ops, &RemoveOperation{
+			Path: path.Append(k).String(),
+		})
+	}
+
+	for k, v := range newV {
+		if oldValue, ok := oldV[k]; ok {
+			d.diff(path.Append(k), oldValue, v)
+			continue
+		}
+		d.ops = append(d.ops, &AddOperation{
+			Path:  path.Append(k).String(),
+			Value: v,
+		})
+	}
+}
+
+func (d *differ) diffList(path JSONPointer, oldV, newV []interface{}) {
+	opts := d.listOptions(path)
+	switch opts.Strategy {
+	case ListByIndex:
+		d.diffListByIndex(path, oldV, newV)
+		return
+	case ListByMergeKey:
+		if d.diffListByMergeKey(path, oldV, newV, opts.MergeKey) {
+			return
+		}
+	}
+
+	if reflect.DeepEqual(oldV, newV) {
+		return
+	}
+	d.ops = append(d.ops, &ReplaceOperation{
+		Path:  path.String(),
+		Value: newV,
+	})
+}
+
+func (d *differ) diffListByIndex(path JSONPointer, oldV, newV []interface{}) {
+	for i := 0; i < len(oldV) && i < len(newV); i++ {
+		d.diff(path.Append(strconv.Itoa(i)), oldV[i], newV[i])
+	}
+	for i := len(oldV) - 1; i >= len(newV); i-- {
+		d.ops = append(d.ops, &RemoveOperation{
+			Path: path.Append(strconv.Itoa(i)).String(),
+		})
+	}
+	for i := len(oldV); i < len(newV); i++ {
+		d.ops = append(d.ops, &AddOperation{
+			Path:  path.Append("-").String(),
+			Value: newV[i],
+		})
+	}
+}
+
+// diffListByMergeKey matches items by the merge key, moving kept items into new order.
+// Returns false when some item doesn't have the merge key.
+func (d *differ) diffListByMergeKey(path JSONPointer, oldV, newV []interface{}, mergeKey string) bool {
+	oldKeys, ok := listMergeKeys(oldV, mergeKey)
+	if !ok {
+		return false
+	}
+	newKeys, ok := listMergeKeys(newV, mergeKey)
+	if !ok {
+		return false
+	}
+
+	newItems := make(map[string]interface{}, len(newV))
+	for i, k := range newKeys {
+		newItems[k] = newV[i]
+	}
+
+	// Remove items which are gone, from the end so indices stay valid
+	var current []string
+	oldItems := make(map[string]interface{}, len(oldV))
+	for i := len(oldV) - 1; i >= 0; i-- {
+		if _, ok := newItems[oldKeys[i]]; !ok {
+			d.ops = append(d.ops, &RemoveOperation{
+				Path: path.Append(strconv.Itoa(i)).String(),
+			})
+			continue
+		}
+		current = append([]string{oldKeys[i]}, current...)
+		oldItems[oldKeys[i]] = oldV[i]
+	}
+
+	// Walk new order, moving kept items in place and inserting added ones
+	for i, k := range newKeys {
+		itemPath := path.Append(strconv.Itoa(i))
+		oldItem, kept := oldItems[k]
+		if !kept {
+			d.ops = append(d.ops, &AddOperation{
+				Path:  itemPath.String(),
+				Value: newV[i],
+			})
+			current = append(current[:i], append([]string{k}, current[i:]...)...)
+			continue
+		}
+
+		if current[i] != k {
+			from := indexOf(current, k)
+			d.ops = append(d.ops, &MoveOperation{
+				From: path.Append(strconv.Itoa(from)).String(),
+				Path: itemPath.String(),
+			})
+			current = append(current[:from], current[from+1:]...)
+			current = append(current[:i], append([]string{k}, current[i:]...)...)
+		}
+		d.diff(itemPath, oldItem, newV[i])
+	}
+	return true
+}
+
+// listOptions returns options for the list at the path
+func (d *differ) listOptions(path JSONPointer) ListDiffOptions {
+	for pattern, opts := range d.opts.Lists {
+		p, err := ParseJSONPointer(pattern)
+		if err == nil && path.Matches(p) {
+			return opts
+		}
+	}
+	return d.opts.ListDiffOptions
+}
+
+// listMergeKeys returns merge key values of list items, false if some item doesn't have unique one
+func listMergeKeys(l []interface{}, mergeKey string) ([]string, bool) {
+	if mergeKey == "" {
+		return nil, false
+	}
+	keys := make([]string, 0, len(l))
+	seen := make(map[string]bool, len(l))
+	for _, item := range l {
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		v, ok := m[mergeKey]
+		if !ok {
+			return nil, false
+		}
+		k := fmt.Sprintf("%v", v)
+		if seen[k] {
+			return nil, false
+		}
+		seen[k] = true
+		keys = append(keys, k)
+	}
+	return keys, true
+}
+
+func indexOf(l []string, s string) int {
+	for i, v := range l {
+		if v == s {
+			return i
+		}
+	}
+	return -1
+}
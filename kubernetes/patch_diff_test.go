@@ -0,0 +1,154 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	byName := ListDiffOptions{Strategy: ListByMergeKey, MergeKey: "name"}
+
+	cases := []struct {
+		name     string
+		opts     DiffOptions
+		old      string
+		new      string
+		ops      []string
+		live     string
+		expected string
+	}{
+		{
+			name: "replace changed list",
+			old:  `{"args": ["a", "b"]}`,
+			new:  `{"args": ["a", "c"]}`,
+			ops: []string{
+				`{"path":"/spec/args","value":["a","c"],"op":"replace"}`,
+			},
+			live:     `{"args": ["a", "b"], "paused": true}`,
+			expected: `{"args": ["a", "c"], "paused": true}`,
+		},
+		{
+			name:     "keep unchanged list",
+			old:      `{"args": ["a", "b"]}`,
+			new:      `{"args": ["a", "b"]}`,
+			live:     `{"args": ["a", "b"], "paused": true}`,
+			expected: `{"args": ["a", "b"], "paused": true}`,
+		},
+		{
+			name: "shrink list by index",
+			opts: DiffOptions{ListDiffOptions: ListDiffOptions{Strategy: ListByIndex}},
+			old:  `{"args": ["a", "b", "c"]}`,
+			new:  `{"args": ["a", "x"]}`,
+			ops: []string{
+				`{"path":"/spec/args/1","value":"x","op":"replace"}`,
+				`{"path":"/spec/args/2","op":"remove"}`,
+			},
+			live:     `{"args": ["a", "b", "c"]}`,
+			expected: `{"args": ["a", "x"]}`,
+		},
+		{
+			name: "grow list by index",
+			opts: DiffOptions{ListDiffOptions: ListDiffOptions{Strategy: ListByIndex}},
+			old:  `{"args": ["a"]}`,
+			new:  `{"args": ["a", "b", "c"]}`,
+			ops: []string{
+				`{"path":"/spec/args/-","value":"b","op":"add"}`,
+				`{"path":"/spec/args/-","value":"c","op":"add"}`,
+			},
+			live:     `{"args": ["a"]}`,
+			expected: `{"args": ["a", "b", "c"]}`,
+		},
+		{
+			name: "match list items by merge key",
+			opts: DiffOptions{ListDiffOptions: byName},
+			old:  `{"containers": [{"name": "a", "image": "1"}, {"name": "b"}, {"name": "c"}]}`,
+			new:  `{"containers": [{"name": "c"}, {"name": "a", "image": "2"}, {"name": "d"}]}`,
+			ops: []string{
+				`{"path":"/spec/containers/1","op":"remove"}`,
+				`{"from":"/spec/containers/1","path":"/spec/containers/0","op":"move"}`,
+				`{"path":"/spec/containers/1/image","value":"2","op":"replace"}`,
+				`{"path":"/spec/containers/2","value":{"name":"d"},"op":"add"}`,
+			},
+			live:     `{"containers": [{"name": "a", "image": "1", "imagePullPolicy": "Always"}, {"name": "b"}, {"name": "c"}]}`,
+			expected: `{"containers": [{"name": "c"}, {"name": "a", "image": "2", "imagePullPolicy": "Always"}, {"name": "d"}]}`,
+		},
+		{
+			name: "replace list with items missing merge key",
+			opts: DiffOptions{ListDiffOptions: byName},
+			old:  `{"containers": [{"name": "a"}, {"image": "1"}]}`,
+			new:  `{"containers": [{"name": "a"}, {"image": "2"}]}`,
+			ops: []string{
+				`{"path":"/spec/containers","value":[{"name":"a"},{"image":"2"}],"op":"replace"}`,
+			},
+			live:     `{"containers": [{"name": "a"}, {"image": "1"}]}`,
+			expected: `{"containers": [{"name": "a"}, {"image": "2"}]}`,
+		},
+		{
+			name: "override list options by path",
+			opts: DiffOptions{Lists: map[string]ListDiffOptions{
+				"/spec/containers":        byName,
+				"/spec/containers/*/args": {Strategy: ListByIndex},
+			}},
+			old: `{"containers": [{"name": "a", "args": ["x", "y"]}], "args": ["x", "y"]}`,
+			new: `{"containers": [{"name": "a", "args": ["x", "z"]}], "args": ["x", "y"]}`,
+			ops: []string{
+				`{"path":"/spec/containers/0/args/1","value":"z","op":"replace"}`,
+			},
+			live:     `{"containers": [{"name": "a", "args": ["x", "y"]}], "args": ["x", "y"]}`,
+			expected: `{"containers": [{"name": "a", "args": ["x", "z"]}], "args": ["x", "y"]}`,
+		},
+		{
+			name: "leave keys present only in live document",
+			old:  `{"labels": {"app": "web", "tier": "front"}}`,
+			new:  `{"labels": {"app": "api"}}`,
+			ops: []string{
+				`{"path":"/spec/labels/tier","op":"remove"}`,
+				`{"path":"/spec/labels/app","value":"api","op":"replace"}`,
+			},
+			live:     `{"labels": {"app": "web", "tier": "front", "owner": "ops"}, "replicas": 3}`,
+			expected: `{"labels": {"app": "api", "owner": "ops"}, "replicas": 3}`,
+		},
+	}
+
+	for _, c := range cases {
+		var oldV, newV interface{}
+		if err := json.Unmarshal([]byte(c.old), &oldV); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(c.new), &newV); err != nil {
+			t.Fatal(err)
+		}
+
+		ops := Diff("spec", oldV, newV, c.opts)
+		if len(ops) != len(c.ops) {
+			t.Errorf("%s: expected %d operations, got %s", c.name, len(c.ops), ops)
+			continue
+		}
+		for i, op := range ops {
+			b, err := op.MarshalJSON()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(b) != c.ops[i] {
+				t.Errorf("%s: operation #%d: expected %s, got %s", c.name, i, c.ops[i], b)
+			}
+		}
+
+		patched, err := ops.Apply([]byte(`{"spec": ` + c.live + `}`))
+		if err != nil {
+			t.Errorf("%s: failed to apply operations: %s", c.name, err)
+			continue
+		}
+		var actual, expected interface{}
+		if err := json.Unmarshal(patched, &actual); err != nil {
+			t.Fatal(err)
+		}
+		if err := json.Unmarshal([]byte(`{"spec": `+c.expected+`}`), &expected); err != nil {
+			t.Fatal(err)
+		}
+		if !reflect.DeepEqual(actual, expected) {
+			t.Errorf("%s: expected %s, got %s", c.name, c.expected, patched)
+		}
+	}
+}
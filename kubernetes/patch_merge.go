@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/strategicpatch"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PatchStrategy selects how changes of the resource are sent to API
+type PatchStrategy string
+
+const (
+	// JSONPatchStrategy sends RFC 6902 JSON patch
+	JSONPatchStrategy PatchStrategy = "json"
+	// MergePatchStrategy sends RFC 7386 JSON merge patch
+	MergePatchStrategy PatchStrategy = "merge"
+	// StrategicMergePatchStrategy sends k8s strategic merge patch respecting patchMergeKey of typed objects
+	StrategicMergePatchStrategy PatchStrategy = "strategic"
+)
+
+// CreatePatch produces patch of given strategy turning old value into new one.
+// Values are expanded TF values or typed objects, dataStruct is typed object
+// (e.g. &appsv1.Deployment{}) required by strategic merge patch only.
+func CreatePatch(strategy PatchStrategy, oldV, newV interface{}, dataStruct interface{}) (client.Patch, error) {
+	switch strategy {
+	case JSONPatchStrategy:
+		return CreateJSONPatch(oldV, newV, DiffOptions{})
+	case MergePatchStrategy:
+		return CreateMergePatch(oldV, newV)
+	case StrategicMergePatchStrategy:
+		return CreateStrategicMergePatch(oldV, newV, dataStruct)
+	}
+	return nil, fmt.Errorf("Unknown patch strategy: %q", strategy)
+}
+
+// CreateJSONPatch produces RFC 6902 JSON patch turning old value into new one
+func CreateJSONPatch(oldV, newV interface{}, opts DiffOptions) (client.Patch, error) {
+	oldN, newN, err := normalizeJSONValues(oldV, newV)
+	if err != nil {
+		return nil, err
+	}
+	return Diff("", oldN, newN, opts), nil
+}
+
+// CreateMergePatch produces RFC 7386 JSON merge patch turning old value into new one.
+// Only keys present in old or new value are touched.
+func CreateMergePatch(oldV, newV interface{}) (client.Patch, error) {
+	oldN, newN, err := normalizeJSONValues(oldV, newV)
+	if err != nil {
+		return nil, err
+	}
+
+	patch := mergePatchDiff(oldN, newN)
+	if patch == nil {
+		patch = map[string]interface{}{}
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to marshal merge patch: %s", err)
+	}
+	return client.RawPatch(types.MergePatchType, data), nil
+}
+
+// CreateStrategicMergePatch produces k8s strategic merge patch turning old value into new one.
+// dataStruct is typed object whose patchMergeKey and patchStrategy tags drive the patch.
+func CreateStrategicMergePatch(oldV, newV interface{}, dataStruct interface{}) (client.Patch, error) {
+	if dataStruct == nil {
+		return nil, fmt.Errorf("Strategic merge patch requires typed object")
+	}
+	oldJSON, err := json.Marshal(oldV)
+	if err != nil {
+		return nil, err
+	}
+	newJSON, err := json.Marshal(newV)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := strategicpatch.CreateTwoWayMergePatch(oldJSON, newJSON, dataStruct)
+	if err != nil {
+		return nil, fmt.Errorf("Failed to create strategic merge patch: %s", err)
+	}
+	return client.RawPatch(types.StrategicMergePatchType, data), nil
+}
+
+// mergePatchDiff produces merge patch document, nil when there's nothing to change
+func mergePatchDiff(oldV, newV interface{}) interface{} {
+	o, oldIsMap := oldV.(map[string]interface{})
+	n, newIsMap := newV.(map[string]interface{})
+	if !oldIsMap || !newIsMap {
+		if jsonEqual(oldV, newV) {
+			return nil
+		}
+		return newV
+	}
+
+	patch := map[string]interface{}{}
+	for k := range o {
+		if _, ok := n[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, v := range n {
+		oldValue, ok := o[k]
+		if !ok {
+			patch[k] = v
+			continue
+		}
+		if p := mergePatchDiff(oldValue, v); p != nil || (v == nil && oldValue != nil) {
+			patch[k] = p
+		}
+	}
+	if len(patch) == 0 {
+		return nil
+	}
+	return patch
+}
+
+// normalizeJSONValues converts typed objects into JSON maps, lists and scalars
+func normalizeJSONValues(oldV, newV interface{}) (interface{}, interface{}, error) {
+	oldN, err := normalizeJSONValue(oldV)
+	if err != nil {
+		return nil, nil, err
+	}
+	newN, err := normalizeJSONValue(newV)
+	if err != nil {
+		return nil, nil, err
+	}
+	return oldN, newN, nil
+}
@@ -0,0 +1,74 @@
+package kubernetes
+
+import "testing"
+
+func TestCreateMergePatch(t *testing.T) {
+	cases := []struct {
+		name  string
+		old   interface{}
+		new   interface{}
+		patch string
+	}{
+		{
+			name:  "removed key is deleted by null",
+			old:   map[string]interface{}{"a": "1", "b": "2"},
+			new:   map[string]interface{}{"a": "1"},
+			patch: `{"b":null}`,
+		},
+		{
+			name:  "key set to null is deleted",
+			old:   map[string]interface{}{"a": "1", "b": "2"},
+			new:   map[string]interface{}{"a": "1", "b": nil},
+			patch: `{"b":null}`,
+		},
+		{
+			name: "nested maps contain only changes",
+			old: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels":      map[string]interface{}{"app": "web", "tier": "front"},
+					"annotations": map[string]interface{}{"note": "x"},
+				},
+			},
+			new: map[string]interface{}{
+				"metadata": map[string]interface{}{
+					"labels":      map[string]interface{}{"app": "api", "tier": "front", "team": "ops"},
+					"annotations": map[string]interface{}{"note": "x"},
+				},
+			},
+			patch: `{"metadata":{"labels":{"app":"api","team":"ops"}}}`,
+		},
+		{
+			name:  "lists are replaced",
+			old:   map[string]interface{}{"args": []interface{}{"a", "b"}},
+			new:   map[string]interface{}{"args": []interface{}{"a"}},
+			patch: `{"args":["a"]}`,
+		},
+		{
+			name:  "map replaced by scalar",
+			old:   map[string]interface{}{"a": map[string]interface{}{"b": "c"}},
+			new:   map[string]interface{}{"a": "b"},
+			patch: `{"a":"b"}`,
+		},
+		{
+			name:  "unchanged",
+			old:   map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1, 2}}, "c": nil},
+			new:   map[string]interface{}{"a": map[string]interface{}{"b": []interface{}{1, 2}}, "c": nil},
+			patch: `{}`,
+		},
+	}
+
+	for _, c := range cases {
+		patch, err := CreateMergePatch(c.old, c.new)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.name, err)
+			continue
+		}
+		data, err := patch.Data(nil)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != c.patch {
+			t.Errorf("%s: expected %s, got %s", c.name, c.patch, data)
+		}
+	}
+}
@@ -1,70 +1,21 @@
 package kubernetes
 
 import (
+	"context"
 	"encoding/json"
-	"reflect"
 	"sort"
-	"strings"
+
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
 func diffStringMap(pathPrefix string, oldV, newV map[string]interface{}) PatchOperations {
-	ops := make([]PatchOperation, 0, 0)
-
-	pathPrefix = strings.TrimRight(pathPrefix, "/")
-
-	// If old value was empty, just create the object
-	if len(oldV) == 0 {
-		ops = append(ops, &AddOperation{
-			Path:  pathPrefix,
-			Value: newV,
-		})
-		return ops
-	}
-
 	// This is suboptimal for adding whole new map from scratch
 	// or deleting the whole map, but it's actually intention.
 	// There may be some other map items managed outside of TF
 	// and we don't want to touch these.
-
-	for k := range oldV {
-		if _, ok := newV[k]; ok {
-			continue
-		}
-		ops = append(ops, &RemoveOperation{
-			Path: pathPrefix + "/" + escapeJSONPointer(k),
-		})
-	}
-
-	for k, v := range newV {
-		newValue := v.(string)
-
-		if oldValue, ok := oldV[k].(string); ok {
-			if oldValue == newValue {
-				continue
-			}
-
-			ops = append(ops, &ReplaceOperation{
-				Path:  pathPrefix + "/" + escapeJSONPointer(k),
-				Value: newValue,
-			})
-			continue
-		}
-
-		ops = append(ops, &AddOperation{
-			Path:  pathPrefix + "/" + escapeJSONPointer(k),
-			Value: newValue,
-		})
-	}
-
-	return ops
-}
-
-// escapeJSONPointer escapes string per RFC 6901
-// so it can be used as path in JSON patch operations
-func escapeJSONPointer(path string) string {
-	path = strings.Replace(path, "~", "~0", -1)
-	path = strings.Replace(path, "/", "~1", -1)
-	return path
+	return Diff(pathPrefix, oldV, newV, DiffOptions{})
 }
 
 // PatchOperations is array of patch operations
@@ -76,23 +27,74 @@ func (po PatchOperations) MarshalJSON() ([]byte, error) {
 	return json.Marshal(v)
 }
 
-// Equal compares operations
-func (po PatchOperations) Equal(ops []PatchOperation) bool {
-	var v []PatchOperation = po
+// Type implements client.Patch, so operations can be passed to client's Patch directly
+func (po PatchOperations) Type() types.PatchType {
+	return types.JSONPatchType
+}
+
+// Data implements client.Patch
+func (po PatchOperations) Data(obj runtime.Object) ([]byte, error) {
+	if len(po) == 0 {
+		return []byte("[]"), nil
+	}
+	return po.MarshalJSON()
+}
 
-	sort.Slice(v, sortByPathAsc(v))
-	sort.Slice(ops, sortByPathAsc(ops))
+// PatchObject sends operations to API and returns the object updated with the response.
+// Empty patch is a no-op and object is returned as is.
+func PatchObject(ctx context.Context, conn client.Client, obj runtime.Object, ops PatchOperations, opts ...client.PatchOption) (runtime.Object, error) {
+	if len(ops) == 0 {
+		return obj, nil
+	}
+	if err := conn.Patch(ctx, obj, ops, opts...); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// Equal compares operations regardless of their order, inputs are not modified
+func (po PatchOperations) Equal(ops []PatchOperation) bool {
+	a := canonicalEntries(po)
+	b := canonicalEntries(ops)
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i].json != b[i].json {
+			return false
+		}
+	}
+	return true
+}
 
-	return reflect.DeepEqual(v, ops)
+// PrependResourceVersionTest puts test of metadata.resourceVersion in front of operations,
+// so the patch fails when object was modified since it was read
+func PrependResourceVersionTest(ops PatchOperations, resourceVersion string) PatchOperations {
+	return PrependFieldTests(ops, map[string]interface{}{
+		"/metadata/resourceVersion": resourceVersion,
+	})
 }
 
-func sortByPathAsc(ops []PatchOperation) func(i, j int) bool {
-	return func(i, j int) bool {
-		return ops[i].GetPath() < ops[j].GetPath()
+// PrependFieldTests puts tests of fields at given paths in front of operations,
+// so the patch fails when any of fields doesn't have expected value
+func PrependFieldTests(ops PatchOperations, fields map[string]interface{}) PatchOperations {
+	paths := make([]string, 0, len(fields))
+	for p := range fields {
+		paths = append(paths, p)
+	}
+	sort.Strings(paths)
+
+	out := make([]PatchOperation, 0, len(fields)+len(ops))
+	for _, p := range paths {
+		out = append(out, &TestOperation{
+			Path:  p,
+			Value: fields[p],
+		})
 	}
+	return append(out, ops...)
 }
 
-// PatchOperation interface for Add, Replace, Remove operations
+// PatchOperation interface for Add, Replace, Remove, Test, Move, Copy operations
 type PatchOperation interface {
 	MarshalJSON() ([]byte, error)
 	// GetPath erer
@@ -166,3 +168,72 @@ func (o *RemoveOperation) String() string {
 	b, _ := o.MarshalJSON()
 	return string(b)
 }
+
+// TestOperation tests that value at the path is equal to the given one
+type TestOperation struct {
+	Path  string      `json:"path"`
+	Value interface{} `json:"value"`
+	Op    string      `json:"op"`
+}
+
+// GetPath returns patch path
+func (o *TestOperation) GetPath() string {
+	return o.Path
+}
+
+// MarshalJSON serializes struct to JSON
+func (o *TestOperation) MarshalJSON() ([]byte, error) {
+	o.Op = "test"
+	return json.Marshal(*o)
+}
+
+func (o *TestOperation) String() string {
+	b, _ := o.MarshalJSON()
+	return string(b)
+}
+
+// MoveOperation moves value from one location to another
+type MoveOperation struct {
+	From string `json:"from"`
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// GetPath returns patch path
+func (o *MoveOperation) GetPath() string {
+	return o.Path
+}
+
+// MarshalJSON serializes struct to JSON
+func (o *MoveOperation) MarshalJSON() ([]byte, error) {
+	o.Op = "move"
+	return json.Marshal(*o)
+}
+
+func (o *MoveOperation) String() string {
+	b, _ := o.MarshalJSON()
+	return string(b)
+}
+
+// CopyOperation copies value from one location to another
+type CopyOperation struct {
+	From string `json:"from"`
+	Path string `json:"path"`
+	Op   string `json:"op"`
+}
+
+// GetPath returns patch path
+func (o *CopyOperation) GetPath() string {
+	return o.Path
+}
+
+// MarshalJSON serializes struct to JSON
+func (o *CopyOperation) MarshalJSON() ([]byte, error) {
+	o.Op = "copy"
+	return json.Marshal(*o)
+}
+
+func (o *CopyOperation) String() string {
+	b, _ := o.MarshalJSON()
+	return string(b)
+}
@@ -0,0 +1,95 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// ParsePatch parses JSON patch document into typed operations,
+// validating operation names, required fields and JSON pointers
+func ParsePatch(data []byte) (PatchOperations, error) {
+	var raw []map[string]json.RawMessage
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, fmt.Errorf("Failed to parse JSON patch: %s", err)
+	}
+
+	ops := make([]PatchOperation, 0, len(raw))
+	for i, r := range raw {
+		op, err := parseOperation(r)
+		if err != nil {
+			return nil, fmt.Errorf("Invalid operation #%d: %s", i, err)
+		}
+		ops = append(ops, op)
+	}
+	return ops, nil
+}
+
+// UnmarshalJSON unmarshals operations from json
+func (po *PatchOperations) UnmarshalJSON(data []byte) error {
+	ops, err := ParsePatch(data)
+	if err != nil {
+		return err
+	}
+	*po = ops
+	return nil
+}
+
+func parseOperation(raw map[string]json.RawMessage) (PatchOperation, error) {
+	var name string
+	if err := parseOperationField(raw, "op", &name); err != nil {
+		return nil, err
+	}
+	var path string
+	if err := parseOperationField(raw, "path", &path); err != nil {
+		return nil, err
+	}
+	if _, err := ParseJSONPointer(path); err != nil {
+		return nil, err
+	}
+
+	switch name {
+	case "add", "replace", "test":
+		rawValue, ok := raw["value"]
+		if !ok {
+			return nil, fmt.Errorf("%q operation requires \"value\"", name)
+		}
+		value, err := decodeJSON(rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("invalid \"value\": %s", err)
+		}
+		switch name {
+		case "add":
+			return &AddOperation{Path: path, Value: value}, nil
+		case "replace":
+			return &ReplaceOperation{Path: path, Value: value}, nil
+		}
+		return &TestOperation{Path: path, Value: value}, nil
+	case "remove":
+		return &RemoveOperation{Path: path}, nil
+	case "move", "copy":
+		var from string
+		if err := parseOperationField(raw, "from", &from); err != nil {
+			return nil, err
+		}
+		if _, err := ParseJSONPointer(from); err != nil {
+			return nil, err
+		}
+		if name == "move" {
+			return &MoveOperation{From: from, Path: path}, nil
+		}
+		return &CopyOperation{From: from, Path: path}, nil
+	}
+
+	return nil, fmt.Errorf("unknown operation %q", name)
+}
+
+func parseOperationField(raw map[string]json.RawMessage, field string, out *string) error {
+	v, ok := raw[field]
+	if !ok {
+		return fmt.Errorf("missing %q", field)
+	}
+	if err := json.Unmarshal(v, out); err != nil {
+		return fmt.Errorf("%q must be a string", field)
+	}
+	return nil
+}
@@ -0,0 +1,133 @@
+package kubernetes
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+func TestParsePatch(t *testing.T) {
+	patch := `[
+		{"op": "test", "path": "/metadata/resourceVersion", "value": "42"},
+		{"op": "add", "path": "/metadata/labels/app.kubernetes.io~1name", "value": "web"},
+		{"op": "replace", "path": "/spec/replicas", "value": 3},
+		{"op": "remove", "path": "/metadata/annotations/obsolete"},
+		{"op": "move", "from": "/spec/containers/1", "path": "/spec/containers/0"},
+		{"op": "copy", "from": "/spec/selector", "path": "/spec/template/metadata/labels"}
+	]`
+	expected := []string{
+		`{"path":"/metadata/resourceVersion","value":"42","op":"test"}`,
+		`{"path":"/metadata/labels/app.kubernetes.io~1name","value":"web","op":"add"}`,
+		`{"path":"/spec/replicas","value":3,"op":"replace"}`,
+		`{"path":"/metadata/annotations/obsolete","op":"remove"}`,
+		`{"from":"/spec/containers/1","path":"/spec/containers/0","op":"move"}`,
+		`{"from":"/spec/selector","path":"/spec/template/metadata/labels","op":"copy"}`,
+	}
+
+	ops, err := ParsePatch([]byte(patch))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(ops) != len(expected) {
+		t.Fatalf("Expected %d operations, got %d", len(expected), len(ops))
+	}
+	for i, op := range ops {
+		b, err := op.MarshalJSON()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != expected[i] {
+			t.Errorf("Operation #%d: expected %s, got %s", i, expected[i], b)
+		}
+	}
+}
+
+func TestParsePatchInvalid(t *testing.T) {
+	cases := []struct {
+		name  string
+		patch string
+		err   string
+	}{
+		{
+			name:  "not a list",
+			patch: `{"op": "add"}`,
+			err:   "Failed to parse JSON patch",
+		},
+		{
+			name:  "unknown operation",
+			patch: `[{"op": "merge", "path": "/a", "value": 1}]`,
+			err:   `Invalid operation #0: unknown operation "merge"`,
+		},
+		{
+			name:  "missing op",
+			patch: `[{"path": "/a", "value": 1}]`,
+			err:   `Invalid operation #0: missing "op"`,
+		},
+		{
+			name:  "missing value",
+			patch: `[{"op": "remove", "path": "/a"}, {"op": "replace", "path": "/a"}]`,
+			err:   `Invalid operation #1: "replace" operation requires "value"`,
+		},
+		{
+			name:  "missing from",
+			patch: `[{"op": "move", "path": "/a"}]`,
+			err:   `Invalid operation #0: missing "from"`,
+		},
+		{
+			name:  "path isn't string",
+			patch: `[{"op": "remove", "path": 1}]`,
+			err:   `Invalid operation #0: "path" must be a string`,
+		},
+		{
+			name:  "invalid path",
+			patch: `[{"op": "remove", "path": "a"}]`,
+			err:   `Invalid operation #0: JSON pointer "a" must start with /`,
+		},
+		{
+			name:  "invalid escape in from",
+			patch: `[{"op": "copy", "from": "/a~2", "path": "/b"}]`,
+			err:   `Invalid operation #0: JSON pointer "/a~2" is invalid`,
+		},
+	}
+
+	for _, c := range cases {
+		_, err := ParsePatch([]byte(c.patch))
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%s: expected error %q, got %v", c.name, c.err, err)
+		}
+	}
+}
+
+func TestPatchOperationsRoundTrip(t *testing.T) {
+	ops := PatchOperations{
+		&TestOperation{Path: "/metadata/resourceVersion", Value: "42"},
+		&AddOperation{Path: "/spec/template/metadata/annotations", Value: map[string]interface{}{"a": "b"}},
+		&ReplaceOperation{Path: "/spec/replicas", Value: 3},
+		&RemoveOperation{Path: "/spec/paused"},
+		&MoveOperation{From: "/spec/containers/1", Path: "/spec/containers/0"},
+		&CopyOperation{From: "/spec/selector", Path: "/spec/template/metadata/labels"},
+	}
+	data, err := json.Marshal(ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var parsed struct {
+		Ops PatchOperations `json:"ops"`
+	}
+	if err := json.Unmarshal([]byte(`{"ops":`+string(data)+`}`), &parsed); err != nil {
+		t.Fatal(err)
+	}
+	out, err := json.Marshal(parsed.Ops)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != string(data) {
+		t.Errorf("Expected %s, got %s", data, out)
+	}
+
+	err = json.Unmarshal([]byte(`[{"op": "add", "path": "/a"}]`), &parsed.Ops)
+	if err == nil || !strings.Contains(err.Error(), `"add" operation requires "value"`) {
+		t.Errorf("Expected UnmarshalJSON to validate operations, got %v", err)
+	}
+}
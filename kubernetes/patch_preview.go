@@ -0,0 +1,143 @@
+package kubernetes
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// previewMaxValueLength limits length of values rendered in patch preview
+const previewMaxValueLength = 120
+
+// previewSensitiveValue replaces masked values in patch preview
+const previewSensitiveValue = "(sensitive value)"
+
+// PreviewOptions customizes RenderPatchPreview
+type PreviewOptions struct {
+	// SensitivePaths are paths whose values and subtrees are masked,
+	// where * matches any path segment, e.g. /spec/template/spec/containers/*/env.
+	// Secret's data and stringData are always masked.
+	SensitivePaths []string
+}
+
+// RenderPatchPreview renders operations applied to the pre-patch document
+// as compact diff showing old → new value per path, e.g. ~ /metadata/labels/app: "old" → "new"
+func RenderPatchPreview(ops PatchOperations, doc []byte, opts PreviewOptions) (string, error) {
+	current, err := decodeJSON(doc)
+	if err != nil {
+		return "", fmt.Errorf("Failed to parse document: %s", err)
+	}
+
+	sensitive := []JSONPointer{}
+	if m, ok := current.(map[string]interface{}); ok && m["kind"] == "Secret" {
+		sensitive = append(sensitive, NewJSONPointer("data"), NewJSONPointer("stringData"))
+	}
+	for _, p := range opts.SensitivePaths {
+		pointer, err := ParseJSONPointer(p)
+		if err != nil {
+			return "", err
+		}
+		sensitive = append(sensitive, pointer)
+	}
+	render := func(path JSONPointer, v interface{}) string {
+		if isSensitivePath(path, sensitive) {
+			return previewSensitiveValue
+		}
+		// Typed values are normalized, so their fields can be masked
+		if normalized, err := normalizeJSONValue(v); err == nil {
+			v = normalized
+		}
+		return previewValue(maskSensitiveValues(path, v, sensitive))
+	}
+
+	var lines []string
+	for i, op := range ops {
+		path, err := ParseJSONPointer(op.GetPath())
+		if err != nil {
+			return "", err
+		}
+		oldValue, oldErr := jsonGet(current, path)
+
+		switch o := op.(type) {
+		case *AddOperation:
+			if oldErr == nil && path.Last() != "-" && !isArray(jsonGetOrNil(current, path.Parent())) {
+				lines = append(lines, fmt.Sprintf("~ %s: %s → %s", path, render(path, oldValue), render(path, o.Value)))
+			} else {
+				lines = append(lines, fmt.Sprintf("+ %s: %s", path, render(path, o.Value)))
+			}
+		case *ReplaceOperation:
+			lines = append(lines, fmt.Sprintf("~ %s: %s → %s", path, render(path, oldValue), render(path, o.Value)))
+		case *RemoveOperation:
+			lines = append(lines, fmt.Sprintf("- %s: %s", path, render(path, oldValue)))
+		case *TestOperation:
+			lines = append(lines, fmt.Sprintf("? %s == %s", path, render(path, o.Value)))
+		case *MoveOperation:
+			lines = append(lines, fmt.Sprintf("> %s → %s", o.From, path))
+		case *CopyOperation:
+			lines = append(lines, fmt.Sprintf("= %s → %s", o.From, path))
+		default:
+			return "", fmt.Errorf("unsupported operation %T", op)
+		}
+
+		current, err = applyOperation(current, op)
+		if err != nil {
+			return "", fmt.Errorf("Failed to apply operation #%d (%v): %s", i, op, err)
+		}
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// isSensitivePath tells if path is at or below any of sensitive paths
+func isSensitivePath(path JSONPointer, sensitive []JSONPointer) bool {
+	for _, s := range sensitive {
+		if len(path) >= len(s) && path[:len(s)].Matches(s) {
+			return true
+		}
+	}
+	return false
+}
+
+// maskSensitiveValues copies value at given path, masking its subtrees at sensitive paths,
+// so the operation on ancestor of sensitive path doesn't reveal the values
+func maskSensitiveValues(path JSONPointer, v interface{}, sensitive []JSONPointer) interface{} {
+	if isSensitivePath(path, sensitive) {
+		return previewSensitiveValue
+	}
+	switch value := v.(type) {
+	case map[string]interface{}:
+		out := make(map[string]interface{}, len(value))
+		for k, item := range value {
+			out[k] = maskSensitiveValues(path.Append(k), item, sensitive)
+		}
+		return out
+	case []interface{}:
+		out := make([]interface{}, len(value))
+		for i, item := range value {
+			out[i] = maskSensitiveValues(path.Append(strconv.Itoa(i)), item, sensitive)
+		}
+		return out
+	}
+	return v
+}
+
+func previewValue(v interface{}) string {
+	s := stringifyJSONValue(v)
+	if len(s) > previewMaxValueLength {
+		return s[:previewMaxValueLength] + "…"
+	}
+	return s
+}
+
+func jsonGetOrNil(doc interface{}, path JSONPointer) interface{} {
+	v, err := jsonGet(doc, path)
+	if err != nil {
+		return nil
+	}
+	return v
+}
+
+func isArray(v interface{}) bool {
+	_, ok := v.([]interface{})
+	return ok
+}
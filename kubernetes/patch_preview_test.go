@@ -0,0 +1,51 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestRenderPatchPreviewMasksAncestorOperations(t *testing.T) {
+	cases := []struct {
+		name string
+		ops  PatchOperations
+		doc  string
+		opts PreviewOptions
+	}{
+		{
+			name: "replace whole secret",
+			ops: PatchOperations{&ReplaceOperation{
+				Path:  "",
+				Value: map[string]interface{}{"kind": "Secret", "data": map[string]string{"k": "TOPSECRET"}},
+			}},
+			doc: `{"kind":"Secret","data":{"k":"OLDSECRET"}}`,
+		},
+		{
+			name: "add container with env",
+			ops: PatchOperations{&AddOperation{
+				Path: "/spec/template/spec/containers/-",
+				Value: map[string]interface{}{
+					"name": "app",
+					"env":  []interface{}{map[string]interface{}{"name": "TOKEN", "value": "TOPSECRET"}},
+				},
+			}},
+			doc:  `{"spec":{"template":{"spec":{"containers":[]}}}}`,
+			opts: PreviewOptions{SensitivePaths: []string{"/spec/template/spec/containers/*/env"}},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := RenderPatchPreview(c.ops, []byte(c.doc), c.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Contains(out, "SECRET") {
+				t.Errorf("Sensitive value revealed: %s", out)
+			}
+			if !strings.Contains(out, previewSensitiveValue) {
+				t.Errorf("Expected masked value: %s", out)
+			}
+		})
+	}
+}
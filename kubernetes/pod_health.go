@@ -0,0 +1,142 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodHealthState is overall state of the pod
+type PodHealthState string
+
+const (
+	// PodHealthy is running pod with all containers ready
+	PodHealthy PodHealthState = "Healthy"
+	// PodProgressing is pod which may still become healthy
+	PodProgressing PodHealthState = "Progressing"
+	// PodFailing is pod which won't become healthy without intervention
+	PodFailing PodHealthState = "Failing"
+)
+
+// PodTerminalWaitingReasons are container waiting reasons considered terminal
+var PodTerminalWaitingReasons = []string{
+	"ImagePullBackOff",
+	"ErrImageNeverPull",
+	"InvalidImageName",
+	"CrashLoopBackOff",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+	"RunContainerError",
+}
+
+// PodHealth describes pod health and the reason of failure
+type PodHealth struct {
+	State PodHealthState
+	// Reason of the failure or progress, e.g. CrashLoopBackOff
+	Reason string
+	// Container affected, if any
+	Container string
+	Message   string
+}
+
+func (h PodHealth) String() string {
+	out := string(h.State)
+	if h.Container != "" {
+		out += fmt.Sprintf(": container %q", h.Container)
+	}
+	if h.Reason != "" {
+		out += ": " + h.Reason
+	}
+	if h.Message != "" {
+		out += ": " + h.Message
+	}
+	return out
+}
+
+// ClassifyPodHealth inspects container statuses and pod conditions
+// and tells progressing pods from terminally failing ones
+func ClassifyPodHealth(pod *api.Pod) PodHealth {
+	switch pod.Status.Phase {
+	case api.PodFailed:
+		return PodHealth{State: PodFailing, Reason: pod.Status.Reason, Message: pod.Status.Message}
+	case api.PodSucceeded:
+		return PodHealth{State: PodHealthy, Reason: string(api.PodSucceeded)}
+	}
+
+	statuses := append([]api.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.State.Waiting == nil {
+			continue
+		}
+		for _, r := range PodTerminalWaitingReasons {
+			if s.State.Waiting.Reason == r {
+				return PodHealth{
+					State:     PodFailing,
+					Reason:    r,
+					Container: s.Name,
+					Message:   s.State.Waiting.Message,
+				}
+			}
+		}
+	}
+
+	for _, c := range pod.Status.Conditions {
+		if c.Type == api.PodScheduled && c.Status == api.ConditionFalse && c.Reason == api.PodReasonUnschedulable {
+			return PodHealth{State: PodFailing, Reason: c.Reason, Message: c.Message}
+		}
+	}
+
+	if isPodReady(pod) {
+		return PodHealth{State: PodHealthy}
+	}
+	return PodHealth{State: PodProgressing, Reason: string(pod.Status.Phase)}
+}
+
+// WaitForPod waits until pod is ready, failing early when pod is terminally failing
+func WaitForPod(ctx context.Context, conn client.Client, key client.ObjectKey, timeout time.Duration) error {
+	gvk := api.SchemeGroupVersion.WithKind("Pod")
+	return waitForObject(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, string, error) {
+		pod := api.Pod{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &pod); err != nil {
+			return false, "", err
+		}
+		health := ClassifyPodHealth(&pod)
+		if health.State == PodFailing {
+			return false, "", fmt.Errorf("pod is failing: %s", health)
+		}
+		return health.State == PodHealthy, health.String(), nil
+	})
+}
+
+// checkPodsFailing returns error describing first terminally failing pod matching the selector.
+// Nothing is checked when selector is nil.
+func checkPodsFailing(conn client.Client, namespace string, labelSelector *metav1.LabelSelector) error {
+	if labelSelector == nil {
+		return nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return err
+	}
+
+	pods := api.PodList{}
+	err = conn.List(context.TODO(), &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return err
+	}
+
+	for _, pod := range pods.Items {
+		health := ClassifyPodHealth(&pod)
+		if health.State == PodFailing {
+			return fmt.Errorf("pod %s is failing: %s", pod.Name, health)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"strings"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func waitingContainer(name, reason string) api.ContainerStatus {
+	return api.ContainerStatus{
+		Name:  name,
+		State: api.ContainerState{Waiting: &api.ContainerStateWaiting{Reason: reason, Message: reason + " message"}},
+	}
+}
+
+func TestClassifyPodHealth(t *testing.T) {
+	cases := []struct {
+		name   string
+		status api.PodStatus
+		health PodHealth
+	}{
+		{
+			name: "image pull back off",
+			status: api.PodStatus{
+				Phase:             api.PodPending,
+				ContainerStatuses: []api.ContainerStatus{waitingContainer("app", "ImagePullBackOff")},
+			},
+			health: PodHealth{State: PodFailing, Reason: "ImagePullBackOff", Container: "app", Message: "ImagePullBackOff message"},
+		},
+		{
+			name: "crash loop in init container",
+			status: api.PodStatus{
+				Phase:                 api.PodPending,
+				InitContainerStatuses: []api.ContainerStatus{waitingContainer("migrate", "CrashLoopBackOff")},
+				ContainerStatuses:     []api.ContainerStatus{waitingContainer("app", "PodInitializing")},
+			},
+			health: PodHealth{State: PodFailing, Reason: "CrashLoopBackOff", Container: "migrate", Message: "CrashLoopBackOff message"},
+		},
+		{
+			name: "unschedulable",
+			status: api.PodStatus{
+				Phase: api.PodPending,
+				Conditions: []api.PodCondition{{
+					Type:    api.PodScheduled,
+					Status:  api.ConditionFalse,
+					Reason:  api.PodReasonUnschedulable,
+					Message: "0/3 nodes are available",
+				}},
+			},
+			health: PodHealth{State: PodFailing, Reason: api.PodReasonUnschedulable, Message: "0/3 nodes are available"},
+		},
+		{
+			name:   "succeeded",
+			status: api.PodStatus{Phase: api.PodSucceeded},
+			health: PodHealth{State: PodHealthy, Reason: string(api.PodSucceeded)},
+		},
+		{
+			name: "progressing",
+			status: api.PodStatus{
+				Phase:             api.PodPending,
+				ContainerStatuses: []api.ContainerStatus{waitingContainer("app", "ContainerCreating")},
+			},
+			health: PodHealth{State: PodProgressing, Reason: string(api.PodPending)},
+		},
+		{
+			name: "ready",
+			status: api.PodStatus{
+				Phase:      api.PodRunning,
+				Conditions: []api.PodCondition{{Type: api.PodReady, Status: api.ConditionTrue}},
+			},
+			health: PodHealth{State: PodHealthy},
+		},
+	}
+
+	for _, c := range cases {
+		health := ClassifyPodHealth(&api.Pod{Status: c.status})
+		if health != c.health {
+			t.Errorf("%s: expected %#v, got %#v", c.name, c.health, health)
+		}
+	}
+}
+
+func TestRolloutStatusIgnoresOldRevisionPods(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment := &appsv1.Deployment{
+		TypeMeta: metav1.TypeMeta{APIVersion: "apps/v1", Kind: "Deployment"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:   "ns",
+			Name:        "web",
+			UID:         "web-uid",
+			Generation:  2,
+			Annotations: map[string]string{deploymentRevisionAnnotation: "2"},
+		},
+		Spec: appsv1.DeploymentSpec{Replicas: int32Ptr(1), Selector: selector},
+		Status: appsv1.DeploymentStatus{
+			ObservedGeneration: 2,
+			Replicas:           2,
+			UpdatedReplicas:    1,
+		},
+	}
+	controller := true
+	replicaSet := func(name, revision, hash string) *appsv1.ReplicaSet {
+		return &appsv1.ReplicaSet{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace:   "ns",
+				Name:        name,
+				Labels:      map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+				Annotations: map[string]string{deploymentRevisionAnnotation: revision},
+				OwnerReferences: []metav1.OwnerReference{{
+					APIVersion: "apps/v1",
+					Kind:       "Deployment",
+					Name:       "web",
+					UID:        "web-uid",
+					Controller: &controller,
+				}},
+			},
+		}
+	}
+	pod := func(name, hash, reason string) *api.Pod {
+		return &api.Pod{
+			ObjectMeta: metav1.ObjectMeta{
+				Namespace: "ns",
+				Name:      name,
+				Labels:    map[string]string{"app": "web", appsv1.DefaultDeploymentUniqueLabelKey: hash},
+			},
+			Status: api.PodStatus{
+				Phase:             api.PodPending,
+				ContainerStatuses: []api.ContainerStatus{waitingContainer("app", reason)},
+			},
+		}
+	}
+
+	content, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deployment)
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj := &unstructured.Unstructured{Object: content}
+
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme,
+		replicaSet("web-old", "1", "old"),
+		replicaSet("web-new", "2", "new"),
+		pod("web-old-1", "old", "CrashLoopBackOff"),
+		pod("web-new-1", "new", "ContainerCreating"),
+	)
+	done, state, err := RolloutStatus(conn, obj)
+	if err != nil {
+		t.Fatalf("Failing pod of the old revision wasn't ignored: %s", err)
+	}
+	if done || state != "1 old replicas are pending termination" {
+		t.Errorf("Unexpected status: %t, %q", done, state)
+	}
+
+	conn = fake.NewFakeClientWithScheme(scheme.Scheme,
+		replicaSet("web-old", "1", "old"),
+		replicaSet("web-new", "2", "new"),
+		pod("web-old-1", "old", "ContainerCreating"),
+		pod("web-new-1", "new", "CrashLoopBackOff"),
+	)
+	_, _, err = RolloutStatus(conn, obj)
+	if err == nil || !strings.Contains(err.Error(), "pod web-new-1 is failing") {
+		t.Errorf("Expected failing pod of the new revision to be reported, got %v", err)
+	}
+}
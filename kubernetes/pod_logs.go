@@ -0,0 +1,183 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"log"
+	"sort"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	batchv1 "k8s.io/api/batch/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// PodLogsMaxBytes limits size of the log fetched per container
+var PodLogsMaxBytes int64 = 16 * 1024
+
+// ContainerLogs is excerpt of container log
+type ContainerLogs struct {
+	Pod       string
+	Container string
+	Previous  bool
+	Lines     []string
+}
+
+// GetContainerLogs fetches last tailLines of pod's container log
+func GetContainerLogs(ctx context.Context, pods corev1client.PodsGetter, pod *api.Pod, container string, tailLines int64, previous bool) (ContainerLogs, error) {
+	logs := ContainerLogs{
+		Pod:       pod.Name,
+		Container: container,
+		Previous:  previous,
+	}
+
+	opts := &api.PodLogOptions{
+		Container:  container,
+		Previous:   previous,
+		TailLines:  &tailLines,
+		LimitBytes: &PodLogsMaxBytes,
+	}
+	stream, err := pods.Pods(pod.Namespace).GetLogs(pod.Name, opts).Context(ctx).Stream()
+	if err != nil {
+		return logs, err
+	}
+	defer stream.Close()
+
+	body, err := ioutil.ReadAll(stream)
+	if err != nil {
+		return logs, err
+	}
+
+	out := strings.TrimRight(string(body), "\n")
+	if out != "" {
+		logs.Lines = strings.Split(out, "\n")
+	}
+	return logs, nil
+}
+
+// GetLogsForObject fetches logs of up to limit most relevant failing pods of the object.
+// Supported objects are Pod, Deployment, StatefulSet, DaemonSet and Job.
+func GetLogsForObject(ctx context.Context, conn client.Client, pods corev1client.PodsGetter, obj runtime.Object, tailLines int64, limit int) ([]ContainerLogs, error) {
+	candidates, err := podsForObject(ctx, conn, obj)
+	if err != nil {
+		return nil, err
+	}
+
+	var failing []api.Pod
+	for _, pod := range candidates {
+		if ClassifyPodHealth(&pod).State != PodHealthy {
+			failing = append(failing, pod)
+		}
+	}
+
+	// Terminally failing pods go first, then the ones restarting most
+	sort.SliceStable(failing, func(i, j int) bool {
+		iFailing := ClassifyPodHealth(&failing[i]).State == PodFailing
+		jFailing := ClassifyPodHealth(&failing[j]).State == PodFailing
+		if iFailing != jFailing {
+			return iFailing
+		}
+		return podRestartCount(&failing[i]) > podRestartCount(&failing[j])
+	})
+	if limit >= 0 && len(failing) > limit {
+		failing = failing[:limit]
+	}
+
+	var logs []ContainerLogs
+	for i := range failing {
+		logs = append(logs, getPodLogs(ctx, pods, &failing[i], tailLines)...)
+	}
+	return logs, nil
+}
+
+// getPodLogs fetches current and, for restarted containers, previous logs
+// of pod's containers which aren't ready. Failures are only logged.
+func getPodLogs(ctx context.Context, pods corev1client.PodsGetter, pod *api.Pod, tailLines int64) []ContainerLogs {
+	var logs []ContainerLogs
+	statuses := append([]api.ContainerStatus{}, pod.Status.InitContainerStatuses...)
+	statuses = append(statuses, pod.Status.ContainerStatuses...)
+	for _, s := range statuses {
+		if s.Ready || (s.State.Terminated != nil && s.State.Terminated.ExitCode == 0) {
+			continue
+		}
+
+		previous := []bool{false}
+		if s.RestartCount > 0 {
+			previous = append(previous, true)
+		}
+		for _, p := range previous {
+			l, err := GetContainerLogs(ctx, pods, pod, s.Name, tailLines, p)
+			if err != nil {
+				log.Printf("[WARN] Failed to fetch logs of %s/%s (%s): %s", pod.Namespace, pod.Name, s.Name, err)
+				continue
+			}
+			if len(l.Lines) > 0 {
+				logs = append(logs, l)
+			}
+		}
+	}
+	return logs
+}
+
+// podsForObject lists pods belonging to the object
+func podsForObject(ctx context.Context, conn client.Client, obj runtime.Object) ([]api.Pod, error) {
+	var namespace string
+	var labelSelector *metav1.LabelSelector
+	switch o := obj.(type) {
+	case *api.Pod:
+		return []api.Pod{*o}, nil
+	case *appsv1.Deployment:
+		namespace, labelSelector = o.Namespace, o.Spec.Selector
+	case *appsv1.StatefulSet:
+		namespace, labelSelector = o.Namespace, o.Spec.Selector
+	case *appsv1.DaemonSet:
+		namespace, labelSelector = o.Namespace, o.Spec.Selector
+	case *batchv1.Job:
+		namespace, labelSelector = o.Namespace, o.Spec.Selector
+	default:
+		return nil, fmt.Errorf("Unsupported object type: %T", obj)
+	}
+	if labelSelector == nil {
+		return nil, nil
+	}
+
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+	pods := api.PodList{}
+	err = conn.List(ctx, &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+	return pods.Items, nil
+}
+
+func podRestartCount(pod *api.Pod) int32 {
+	var count int32
+	for _, s := range pod.Status.ContainerStatuses {
+		count += s.RestartCount
+	}
+	return count
+}
+
+// StringifyContainerLogs converts container logs into string
+func StringifyContainerLogs(logs []ContainerLogs) string {
+	var output string
+	for _, l := range logs {
+		suffix := ""
+		if l.Previous {
+			suffix = ", previous"
+		}
+		output += fmt.Sprintf("\n   * %s (%s%s): last %d log lines:", l.Pod, l.Container, suffix, len(l.Lines))
+		for _, line := range l.Lines {
+			output += "\n       " + line
+		}
+	}
+	return output
+}
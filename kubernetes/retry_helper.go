@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"log"
+	"strings"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// ConflictRetryBackoff is the backoff used when retrying updates failed with 409 Conflict
+var ConflictRetryBackoff = wait.Backoff{
+	Duration: 100 * time.Millisecond,
+	Factor:   2,
+	Jitter:   0.1,
+	Steps:    5,
+}
+
+// RetryOnConflict calls fn until it doesn't fail with 409 Conflict or backoff steps are exhausted,
+// in which case the last conflict is returned
+func RetryOnConflict(ctx context.Context, fn func() error) error {
+	return retryOn(ctx, apierrors.IsConflict, fn)
+}
+
+// retryOn calls fn until it doesn't fail with retriable error or backoff steps are exhausted
+func retryOn(ctx context.Context, retriable func(err error) bool, fn func() error) error {
+	attempt := 0
+	var lastErr error
+	err := wait.ExponentialBackoff(ConflictRetryBackoff, func() (bool, error) {
+		if ctx.Err() != nil {
+			return false, ctx.Err()
+		}
+		attempt++
+		err := fn()
+		if err == nil {
+			return true, nil
+		}
+		if !retriable(err) {
+			return false, err
+		}
+		lastErr = err
+		log.Printf("[INFO] Update conflicted on attempt %d, retrying: %s", attempt, err)
+		return false, nil
+	})
+	if err == wait.ErrWaitTimeout {
+		return lastErr
+	}
+	return err
+}
+
+// UpdateWithRetry reads the object, changes it via mutate and updates it,
+// starting over with fresh object when update fails with 409 Conflict
+func UpdateWithRetry(ctx context.Context, conn client.Client, key client.ObjectKey, obj runtime.Object, mutate func(obj runtime.Object) error, opts ...client.UpdateOption) error {
+	return RetryOnConflict(ctx, func() error {
+		if err := conn.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		if err := mutate(obj); err != nil {
+			return err
+		}
+		return conn.Update(ctx, obj, opts...)
+	})
+}
+
+// PatchWithRetry reads the object, computes the patch from it and sends it,
+// starting over with fresh object when patch fails with 409 Conflict,
+// or when test operation of PatchOperations fails, e.g. one added by PrependResourceVersionTest.
+// Empty PatchOperations are not sent.
+func PatchWithRetry(ctx context.Context, conn client.Client, key client.ObjectKey, obj runtime.Object, compute func(obj runtime.Object) (client.Patch, error), opts ...client.PatchOption) error {
+	return retryOn(ctx, isPatchConflict, func() error {
+		if err := conn.Get(ctx, key, obj); err != nil {
+			return err
+		}
+		patch, err := compute(obj)
+		if err != nil {
+			return err
+		}
+		if ops, ok := patch.(PatchOperations); ok && len(ops) == 0 {
+			return nil
+		}
+		return conn.Patch(ctx, obj, patch, opts...)
+	})
+}
+
+// jsonPatchRejectedMessage is the message of 422 Invalid returned by API server when JSON patch
+// can't be applied to the object, e.g. when its test operation fails. The reason is not returned.
+const jsonPatchRejectedMessage = "the server rejected our request due to an error in our request"
+
+// jsonPatchTestFailedMessage is the lowercase message prefix of JSON patch library
+// when test operation fails, e.g. `Testing value /metadata/resourceVersion failed`
+const jsonPatchTestFailedMessage = "testing value"
+
+// isPatchConflict tells if patch failed due to concurrent change of the object:
+// 409 Conflict, or 422 Invalid returned by API when test operation of JSON patch fails
+func isPatchConflict(err error) bool {
+	if apierrors.IsConflict(err) {
+		return true
+	}
+	if !apierrors.IsInvalid(err) {
+		return false
+	}
+	message := strings.ToLower(err.Error())
+	return strings.Contains(message, jsonPatchRejectedMessage) || strings.Contains(message, jsonPatchTestFailedMessage)
+}
@@ -0,0 +1,175 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/runtime"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// jsonPatchTestFailedStatus is the response of API server to JSON patch with failing test operation
+const jsonPatchTestFailedStatus = `{
+	"kind": "Status",
+	"apiVersion": "v1",
+	"metadata": {},
+	"status": "Failure",
+	"message": "the server rejected our request due to an error in our request",
+	"reason": "Invalid",
+	"details": {},
+	"code": 422
+}`
+
+const updateConflictStatus = `{
+	"kind": "Status",
+	"apiVersion": "v1",
+	"metadata": {},
+	"status": "Failure",
+	"message": "Operation cannot be fulfilled on configmaps \"config\": the object has been modified; please apply your changes to the latest version and try again",
+	"reason": "Conflict",
+	"details": {"name": "config", "kind": "configmaps"},
+	"code": 409
+}`
+
+type retryTestFailure struct {
+	code int
+	body string
+}
+
+// newRetryTestClient returns client of stand-in API server serving config map ns/config,
+// whose resourceVersion is incremented on every read. First request of the method
+// listed in failures is answered with the failure, later ones succeed.
+func newRetryTestClient(t *testing.T, failures map[string]retryTestFailure, requests *[]string) (client.Client, func()) {
+	resourceVersion := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		*requests = append(*requests, r.Method)
+		w.Header().Set("Content-Type", "application/json")
+		if failure, ok := failures[r.Method]; ok {
+			delete(failures, r.Method)
+			w.WriteHeader(failure.code)
+			w.Write([]byte(failure.body))
+			return
+		}
+		if r.Method == http.MethodGet {
+			resourceVersion++
+		}
+		fmt.Fprintf(w, `{"apiVersion": "v1", "kind": "ConfigMap", "metadata": {"namespace": "ns", "name": "config", "resourceVersion": "%d"}}`, resourceVersion)
+	}))
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(api.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	conn, err := client.New(&rest.Config{Host: srv.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return conn, srv.Close
+}
+
+func TestIsPatchConflict(t *testing.T) {
+	var requests []string
+	conn, closeServer := newRetryTestClient(t, map[string]retryTestFailure{
+		http.MethodPatch: {http.StatusUnprocessableEntity, jsonPatchTestFailedStatus},
+	}, &requests)
+	defer closeServer()
+
+	// Error of the failed test operation as returned by API server
+	obj := &api.ConfigMap{}
+	obj.Namespace = "ns"
+	obj.Name = "config"
+	patch := PrependResourceVersionTest(PatchOperations{}, "1")
+	testFailed := conn.Patch(context.Background(), obj, patch)
+
+	configMaps := apimachineryschema.GroupResource{Resource: "configmaps"}
+	cases := []struct {
+		name     string
+		err      error
+		conflict bool
+	}{
+		{name: "failed test operation", err: testFailed, conflict: true},
+		{name: "conflict", err: apierrors.NewConflict(configMaps, "config", fmt.Errorf("the object has been modified")), conflict: true},
+		{
+			name:     "failed test operation reported by patch library",
+			err:      apierrors.NewInvalid(api.SchemeGroupVersion.WithKind("ConfigMap").GroupKind(), "config", field.ErrorList{field.Invalid(field.NewPath("metadata"), nil, "Testing value /metadata/resourceVersion failed")}),
+			conflict: true,
+		},
+		{
+			name:     "invalid object",
+			err:      apierrors.NewInvalid(api.SchemeGroupVersion.WithKind("ConfigMap").GroupKind(), "config", field.ErrorList{field.Required(field.NewPath("data"), "")}),
+			conflict: false,
+		},
+		{name: "not found", err: apierrors.NewNotFound(configMaps, "config"), conflict: false},
+	}
+
+	for _, c := range cases {
+		if isPatchConflict(c.err) != c.conflict {
+			t.Errorf("%s: expected %t for %v", c.name, c.conflict, c.err)
+		}
+	}
+}
+
+func TestUpdateWithRetry(t *testing.T) {
+	var requests []string
+	conn, closeServer := newRetryTestClient(t, map[string]retryTestFailure{
+		http.MethodPut: {http.StatusConflict, updateConflictStatus},
+	}, &requests)
+	defer closeServer()
+
+	var seen []string
+	obj := &api.ConfigMap{}
+	err := UpdateWithRetry(context.Background(), conn, client.ObjectKey{Namespace: "ns", Name: "config"}, obj, func(obj runtime.Object) error {
+		cm := obj.(*api.ConfigMap)
+		seen = append(seen, cm.ResourceVersion)
+		cm.Data = map[string]string{"a": "b"}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{http.MethodGet, http.MethodPut, http.MethodGet, http.MethodPut}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+	if !reflect.DeepEqual(seen, []string{"1", "2"}) {
+		t.Errorf("Expected update to be retried with re-fetched object, mutated versions %v", seen)
+	}
+}
+
+func TestPatchWithRetry(t *testing.T) {
+	var requests []string
+	conn, closeServer := newRetryTestClient(t, map[string]retryTestFailure{
+		http.MethodPatch: {http.StatusUnprocessableEntity, jsonPatchTestFailedStatus},
+	}, &requests)
+	defer closeServer()
+
+	var seen []string
+	obj := &api.ConfigMap{}
+	err := PatchWithRetry(context.Background(), conn, client.ObjectKey{Namespace: "ns", Name: "config"}, obj, func(obj runtime.Object) (client.Patch, error) {
+		cm := obj.(*api.ConfigMap)
+		seen = append(seen, cm.ResourceVersion)
+		ops := PatchOperations{&AddOperation{Path: "/data", Value: map[string]interface{}{"a": "b"}}}
+		return PrependResourceVersionTest(ops, cm.ResourceVersion), nil
+	})
+	if err != nil {
+		t.Fatalf("Unexpected error: %s", err)
+	}
+
+	expected := []string{http.MethodGet, http.MethodPatch, http.MethodGet, http.MethodPatch}
+	if !reflect.DeepEqual(requests, expected) {
+		t.Errorf("Expected requests %v, got %v", expected, requests)
+	}
+	if !reflect.DeepEqual(seen, []string{"1", "2"}) {
+		t.Errorf("Expected patch to be retried with re-fetched object, computed from versions %v", seen)
+	}
+}
@@ -16,6 +16,16 @@ func MetadataFields(objectName string) map[string]*schema.Schema {
 			Elem:         &schema.Schema{Type: schema.TypeString},
 			ValidateFunc: ValidateAnnotations,
 		},
+		"finalizers": {
+			Type:        schema.TypeSet,
+			Description: fmt.Sprintf("Finalizers which must be done before the %s is deleted. Finalizers added by controllers are left untouched. More info: https://kubernetes.io/docs/concepts/workloads/controllers/garbage-collection/#foreground-cascading-deletion", objectName),
+			Optional:    true,
+			Elem: &schema.Schema{
+				Type:         schema.TypeString,
+				ValidateFunc: ValidateFinalizer,
+			},
+			Set: schema.HashString,
+		},
 		"generation": {
 			Type:        schema.TypeInt,
 			Description: "A sequence number representing a specific generation of the desired state.",
@@ -36,6 +46,14 @@ func MetadataFields(objectName string) map[string]*schema.Schema {
 			Computed:     true,
 			ValidateFunc: ValidateName,
 		},
+		"owner_references": {
+			Type:        schema.TypeList,
+			Description: fmt.Sprintf("List of objects depended by the %s. If all objects in the list have been deleted, the %s will be garbage collected. More info: https://kubernetes.io/docs/concepts/workloads/controllers/garbage-collection/", objectName, objectName),
+			Optional:    true,
+			Elem: &schema.Resource{
+				Schema: OwnerReferenceFields(),
+			},
+		},
 		"resource_version": {
 			Type:        schema.TypeString,
 			Description: fmt.Sprintf("An opaque value that represents the internal version of this %s that can be used by clients to determine when %s has changed. Read more: https://github.com/kubernetes/community/blob/master/contributors/devel/sig-architecture/api-conventions.md#concurrency-control-and-consistency", objectName, objectName),
@@ -113,3 +131,40 @@ func NamespacedMetadataSchema(objectName string, generatableName bool) *schema.S
 		},
 	}
 }
+
+// OwnerReferenceFields composes owner reference fields schema
+func OwnerReferenceFields() map[string]*schema.Schema {
+	return map[string]*schema.Schema{
+		"api_version": {
+			Type:        schema.TypeString,
+			Description: "API version of the referent.",
+			Required:    true,
+		},
+		"block_owner_deletion": {
+			Type:        schema.TypeBool,
+			Description: "If true, the owner cannot be deleted from the key-value store until this reference is removed.",
+			Optional:    true,
+		},
+		"controller": {
+			Type:        schema.TypeBool,
+			Description: "If true, this reference points to the managing controller.",
+			Optional:    true,
+		},
+		"kind": {
+			Type:        schema.TypeString,
+			Description: "Kind of the referent.",
+			Required:    true,
+		},
+		"name": {
+			Type:         schema.TypeString,
+			Description:  "Name of the referent. Referent must be cluster-scoped or live in the same namespace.",
+			Required:     true,
+			ValidateFunc: ValidateName,
+		},
+		"uid": {
+			Type:        schema.TypeString,
+			Description: "UID of the referent. Resolved from the referent's name at apply time when not set.",
+			Optional:    true,
+		},
+	}
+}
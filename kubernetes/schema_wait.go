@@ -0,0 +1,84 @@
+package kubernetes
+
+import (
+	"fmt"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+)
+
+// defaultWaitForTimeout is the timeout used by wait_for block when none is set
+const defaultWaitForTimeout = "10m"
+
+// WaitForSchema produces schema for wait_for block describing when the object is ready
+func WaitForSchema(objectName string) *schema.Schema {
+	return &schema.Schema{
+		Type:        schema.TypeList,
+		Description: fmt.Sprintf("Describes when the %s is considered ready. Terraform waits for all listed criteria to be met.", objectName),
+		Optional:    true,
+		MaxItems:    1,
+		Elem: &schema.Resource{
+			Schema: map[string]*schema.Schema{
+				"condition": {
+					Type:        schema.TypeList,
+					Description: fmt.Sprintf("Status condition of the %s to wait for", objectName),
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"type": {
+								Type:        schema.TypeString,
+								Description: "Type of the condition, e.g. Ready",
+								Required:    true,
+							},
+							"status": {
+								Type:         schema.TypeString,
+								Description:  "Expected status of the condition, one of True, False, Unknown",
+								Optional:     true,
+								Default:      "True",
+								ValidateFunc: ValidateConditionStatus,
+							},
+							"terminal_reasons": {
+								Type:        schema.TypeList,
+								Description: "Reasons which stop the wait with an error when the condition is False",
+								Optional:    true,
+								Elem:        &schema.Schema{Type: schema.TypeString},
+							},
+						},
+					},
+				},
+				"field": {
+					Type:        schema.TypeList,
+					Description: fmt.Sprintf("Field of the %s which has to be equal to the value", objectName),
+					Optional:    true,
+					Elem: &schema.Resource{
+						Schema: map[string]*schema.Schema{
+							"path": {
+								Type:         schema.TypeString,
+								Description:  "JSONPath-like path of the field, e.g. status.phase or {.status.containerStatuses[0].ready}",
+								Required:     true,
+								ValidateFunc: ValidateFieldPath,
+							},
+							"value": {
+								Type:        schema.TypeString,
+								Description: "Expected value of the field",
+								Required:    true,
+							},
+						},
+					},
+				},
+				"rollout": {
+					Type:        schema.TypeBool,
+					Description: "Wait for rollout of Deployment, StatefulSet or DaemonSet to complete, like `kubectl rollout status`",
+					Optional:    true,
+					Default:     false,
+				},
+				"timeout": {
+					Type:         schema.TypeString,
+					Description:  "How long to wait for the criteria to be met, e.g. 30s or 10m",
+					Optional:     true,
+					Default:      defaultWaitForTimeout,
+					ValidateFunc: ValidateDuration,
+				},
+			},
+		},
+	}
+}
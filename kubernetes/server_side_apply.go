@@ -0,0 +1,170 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// applyConflictManagerRe extracts manager name from conflict message,
+// e.g. `conflict with "kubectl" using apps/v1`
+var applyConflictManagerRe = regexp.MustCompile(`conflict with "([^"]*)"`)
+
+// ApplyConflict is a field owned by another field manager
+type ApplyConflict struct {
+	Field   string
+	Manager string
+	Message string
+}
+
+// ApplyConflictError is returned by ServerSideApply when fields are owned by other managers
+type ApplyConflictError struct {
+	Conflicts []ApplyConflict
+	Err       error
+}
+
+func (e *ApplyConflictError) Error() string {
+	if len(e.Conflicts) == 0 {
+		return e.Err.Error()
+	}
+	output := "Apply failed with conflicts, fields are owned by other managers:"
+	for _, c := range e.Conflicts {
+		output += fmt.Sprintf("\n   * %s (%s)", c.Field, c.Manager)
+	}
+	return output
+}
+
+// ServerSideApply applies desired object using server-side apply as the field manager.
+// Object is updated with the response. When force is set, conflicting fields are taken over,
+// otherwise conflicts are returned as *ApplyConflictError.
+func ServerSideApply(ctx context.Context, conn client.Client, obj runtime.Object, fieldManager string, force bool) error {
+	if obj.GetObjectKind().GroupVersionKind().Empty() {
+		gvk, err := apiutil.GVKForObject(obj, scheme.Scheme)
+		if err != nil {
+			return err
+		}
+		obj.GetObjectKind().SetGroupVersionKind(gvk)
+	}
+	// API rejects apply of objects with managed fields set
+	if accessor, err := meta.Accessor(obj); err == nil {
+		accessor.SetManagedFields(nil)
+	}
+
+	opts := []client.PatchOption{client.FieldOwner(fieldManager)}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	err := conn.Patch(ctx, obj, client.Apply, opts...)
+	if err != nil && apierrors.IsConflict(err) {
+		return newApplyConflictError(err)
+	}
+	return err
+}
+
+func newApplyConflictError(err error) error {
+	out := &ApplyConflictError{Err: err}
+	status, ok := err.(apierrors.APIStatus)
+	if !ok || status.Status().Details == nil {
+		return out
+	}
+	for _, c := range status.Status().Details.Causes {
+		if c.Type != metav1.CauseTypeFieldManagerConflict {
+			continue
+		}
+		conflict := ApplyConflict{
+			Field:   c.Field,
+			Message: c.Message,
+		}
+		if m := applyConflictManagerRe.FindStringSubmatch(c.Message); m != nil {
+			conflict.Manager = m[1]
+		}
+		out.Conflicts = append(out.Conflicts, conflict)
+	}
+	return out
+}
+
+// ManagedFields is tree of fields owned by a field manager. Keys are field names,
+// or keys of list items like k:{"name":"app"} as used by managedFields.
+type ManagedFields map[string]ManagedFields
+
+// GetManagedFields returns fields of the object applied by the field manager
+func GetManagedFields(obj metav1.Object, fieldManager string) (ManagedFields, error) {
+	out := ManagedFields{}
+	for _, entry := range obj.GetManagedFields() {
+		if entry.Manager != fieldManager || entry.Operation != metav1.ManagedFieldsOperationApply {
+			continue
+		}
+		if entry.FieldsV1 == nil {
+			continue
+		}
+		var raw map[string]interface{}
+		if err := json.Unmarshal(entry.FieldsV1.Raw, &raw); err != nil {
+			return nil, fmt.Errorf("Failed to parse managed fields of %q: %s", fieldManager, err)
+		}
+		out.merge(raw)
+	}
+	return out, nil
+}
+
+// Has tells if field at the path is managed
+func (f ManagedFields) Has(path ...string) bool {
+	current := f
+	for _, p := range path {
+		next, ok := current[p]
+		if !ok {
+			return false
+		}
+		current = next
+	}
+	return true
+}
+
+// Child returns managed fields under given field
+func (f ManagedFields) Child(name string) ManagedFields {
+	return f[name]
+}
+
+// Paths lists all managed leaf fields as dotted paths
+func (f ManagedFields) Paths() []string {
+	var out []string
+	for k, v := range f {
+		if len(v) == 0 {
+			out = append(out, k)
+			continue
+		}
+		for _, p := range v.Paths() {
+			out = append(out, k+"."+p)
+		}
+	}
+	sort.Strings(out)
+	return out
+}
+
+func (f ManagedFields) merge(raw map[string]interface{}) {
+	for k, v := range raw {
+		// "." marks the list item or map itself
+		if k == "." {
+			continue
+		}
+		name := strings.TrimPrefix(k, "f:")
+		child, ok := f[name]
+		if !ok {
+			child = ManagedFields{}
+			f[name] = child
+		}
+		if m, ok := v.(map[string]interface{}); ok {
+			child.merge(m)
+		}
+	}
+}
@@ -0,0 +1,88 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	api "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// applyConflictStatus is the response of API server to apply of fields owned by other managers
+const applyConflictStatus = `{
+	"kind": "Status",
+	"apiVersion": "v1",
+	"metadata": {},
+	"status": "Failure",
+	"message": "Apply failed with 2 conflicts: conflict with \"kubectl-client-side-apply\" using v1: .data.a\nconflict with \"helm\" using v1: .metadata.labels.app",
+	"reason": "Conflict",
+	"details": {
+		"causes": [
+			{"reason": "FieldManagerConflict", "message": "conflict with \"kubectl-client-side-apply\" using v1", "field": ".data.a"},
+			{"reason": "FieldManagerConflict", "message": "conflict with \"helm\" using v1", "field": ".metadata.labels.app"}
+		]
+	},
+	"code": 409
+}`
+
+func TestServerSideApplyConflict(t *testing.T) {
+	var contentType, fieldManager string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		contentType = r.Header.Get("Content-Type")
+		fieldManager = r.URL.Query().Get("fieldManager")
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusConflict)
+		w.Write([]byte(applyConflictStatus))
+	}))
+	defer srv.Close()
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(api.SchemeGroupVersion.WithKind("ConfigMap"), meta.RESTScopeNamespace)
+	conn, err := client.New(&rest.Config{Host: srv.URL}, client.Options{Scheme: scheme.Scheme, Mapper: mapper})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	obj := &api.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "config", Labels: map[string]string{"app": "web"}},
+		Data:       map[string]string{"a": "b"},
+	}
+	err = ServerSideApply(context.Background(), conn, obj, "terraform", false)
+	if contentType != string(client.Apply.Type()) || fieldManager != "terraform" {
+		t.Errorf("Unexpected apply request: %s, field manager %q", contentType, fieldManager)
+	}
+
+	conflictErr, ok := err.(*ApplyConflictError)
+	if !ok {
+		t.Fatalf("Expected *ApplyConflictError, got %#v", err)
+	}
+	expected := []ApplyConflict{
+		{Field: ".data.a", Manager: "kubectl-client-side-apply", Message: `conflict with "kubectl-client-side-apply" using v1`},
+		{Field: ".metadata.labels.app", Manager: "helm", Message: `conflict with "helm" using v1`},
+	}
+	if !reflect.DeepEqual(conflictErr.Conflicts, expected) {
+		t.Errorf("Expected conflicts %#v, got %#v", expected, conflictErr.Conflicts)
+	}
+	message := "Apply failed with conflicts, fields are owned by other managers:" +
+		"\n   * .data.a (kubectl-client-side-apply)" +
+		"\n   * .metadata.labels.app (helm)"
+	if err.Error() != message {
+		t.Errorf("Unexpected message: %s", err)
+	}
+}
+
+func TestNewApplyConflictErrorWithoutCauses(t *testing.T) {
+	err := newApplyConflictError(errors.New("conflict"))
+	conflictErr := err.(*ApplyConflictError)
+	if len(conflictErr.Conflicts) != 0 || err.Error() != "conflict" {
+		t.Errorf("Unexpected error: %#v", err)
+	}
+}
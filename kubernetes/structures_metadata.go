@@ -2,11 +2,16 @@ package kubernetes
 
 import (
 	"fmt"
+	"log"
 	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
 	"strings"
 
 	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 )
 
 // ExpandMetadata converts terraform object to k8s ObjectMeta
@@ -25,6 +30,14 @@ func ExpandMetadata(in []interface{}) metav1.ObjectMeta {
 		meta.Labels = expandStringMap(m["labels"].(map[string]interface{}))
 	}
 
+	if v, ok := m["finalizers"].(*schema.Set); ok && v.Len() > 0 {
+		meta.Finalizers = expandStringSlice(v.List())
+	}
+
+	if v, ok := m["owner_references"].([]interface{}); ok && len(v) > 0 {
+		meta.OwnerReferences = ExpandOwnerReferences(v)
+	}
+
 	if v, ok := m["generate_name"]; ok {
 		meta.GenerateName = v.(string)
 	}
@@ -47,12 +60,15 @@ func FlattenMetadata(meta metav1.ObjectMeta, d *schema.ResourceData, metaPrefix
 	}
 	configAnnotations := d.Get(prefix + "metadata.0.annotations").(map[string]interface{})
 	m["annotations"] = removeInternalKeys(meta.Annotations, configAnnotations)
+	configFinalizers, _ := d.Get(prefix + "metadata.0.finalizers").(*schema.Set)
+	m["finalizers"] = managedFinalizers(meta.Finalizers, configFinalizers)
 	if meta.GenerateName != "" {
 		m["generate_name"] = meta.GenerateName
 	}
 	configLabels := d.Get(prefix + "metadata.0.labels").(map[string]interface{})
 	m["labels"] = removeInternalKeys(meta.Labels, configLabels)
 	m["name"] = meta.Name
+	m["owner_references"] = FlattenOwnerReferences(meta.OwnerReferences, d, prefix+"metadata.0.owner_references")
 	m["resource_version"] = meta.ResourceVersion
 	m["self_link"] = meta.SelfLink
 	m["uid"] = fmt.Sprintf("%v", meta.UID)
@@ -65,8 +81,44 @@ func FlattenMetadata(meta metav1.ObjectMeta, d *schema.ResourceData, metaPrefix
 	return []interface{}{m}
 }
 
-// PatchMetadata produces set of patch operations for metadata
+// PatchMetadata produces set of patch operations for metadata annotations and labels.
+// Finalizers and owner references can't be patched without current object, their changes
+// are skipped with an error logged, use PatchMetadataWithObject to patch them.
 func PatchMetadata(keyPrefix, pathPrefix string, d *schema.ResourceData) PatchOperations {
+	for _, k := range []string{"finalizers", "owner_references"} {
+		if d.HasChange(keyPrefix + k) {
+			log.Printf("[ERROR] Change of %s%s is skipped, use PatchMetadataWithObject to patch it", keyPrefix, k)
+		}
+	}
+	return patchMetadataMaps(keyPrefix, pathPrefix, d)
+}
+
+// PatchMetadataWithObject produces set of patch operations for metadata.
+// Current object is needed to patch finalizers and owner references, as their positions
+// in the list are needed to remove them. Error is returned when they changed and current is nil.
+func PatchMetadataWithObject(keyPrefix, pathPrefix string, d *schema.ResourceData, current metav1.Object) (PatchOperations, error) {
+	ops := patchMetadataMaps(keyPrefix, pathPrefix, d)
+	if d.HasChange(keyPrefix + "owner_references") {
+		if isNilObject(current) {
+			return nil, fmt.Errorf("Failed to patch %sowner_references: current object is not known", keyPrefix)
+		}
+		oldV, newV := d.GetChange(keyPrefix + "owner_references")
+		diffOps := diffOwnerReferences(pathPrefix+"ownerReferences", current.GetOwnerReferences(), oldV.([]interface{}), newV.([]interface{}))
+		ops = append(ops, diffOps...)
+	}
+	if d.HasChange(keyPrefix + "finalizers") {
+		if isNilObject(current) {
+			return nil, fmt.Errorf("Failed to patch %sfinalizers: current object is not known", keyPrefix)
+		}
+		oldV, newV := d.GetChange(keyPrefix + "finalizers")
+		diffOps := diffFinalizers(pathPrefix+"finalizers", current.GetFinalizers(), oldV.(*schema.Set), newV.(*schema.Set))
+		ops = append(ops, diffOps...)
+	}
+	return ops, nil
+}
+
+// patchMetadataMaps produces patch operations for annotations and labels
+func patchMetadataMaps(keyPrefix, pathPrefix string, d *schema.ResourceData) PatchOperations {
 	ops := make([]PatchOperation, 0, 0)
 	if d.HasChange(keyPrefix + "annotations") {
 		oldV, newV := d.GetChange(keyPrefix + "annotations")
@@ -81,6 +133,213 @@ func PatchMetadata(keyPrefix, pathPrefix string, d *schema.ResourceData) PatchOp
 	return ops
 }
 
+// isNilObject tells if object is nil, including typed nil pointers
+func isNilObject(obj metav1.Object) bool {
+	if obj == nil {
+		return true
+	}
+	v := reflect.ValueOf(obj)
+	return v.Kind() == reflect.Ptr && v.IsNil()
+}
+
+// diffFinalizers produces patch operations adding and removing finalizers managed by TF.
+// There may be some other finalizers added by controllers and we don't want to touch these.
+func diffFinalizers(path string, current []string, oldV, newV *schema.Set) PatchOperations {
+	listPath := parseJSONPointerPrefix(path)
+	remove := expandStringSlice(oldV.Difference(newV).List())
+	ops := removeFinalizersOperations(listPath, current, remove)
+
+	add := make([]string, 0, 0)
+	for _, f := range expandStringSlice(newV.Difference(oldV).List()) {
+		if !isStringInSlice(f, current) {
+			add = append(add, f)
+		}
+	}
+	if len(add) == 0 {
+		return ops
+	}
+	sort.Strings(add)
+
+	if len(current) == 0 {
+		return append(ops, &AddOperation{
+			Path:  listPath.String(),
+			Value: add,
+		})
+	}
+	for _, f := range add {
+		ops = append(ops, &AddOperation{
+			Path:  listPath.Append("-").String(),
+			Value: f,
+		})
+	}
+	return ops
+}
+
+// ExpandOwnerReferences converts terraform list to k8s owner references
+func ExpandOwnerReferences(in []interface{}) []metav1.OwnerReference {
+	refs := make([]metav1.OwnerReference, 0, len(in))
+	for _, r := range in {
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		ref := metav1.OwnerReference{
+			APIVersion: m["api_version"].(string),
+			Kind:       m["kind"].(string),
+			Name:       m["name"].(string),
+		}
+		if v, ok := m["uid"].(string); ok {
+			ref.UID = types.UID(v)
+		}
+		if v, ok := m["controller"].(bool); ok && v {
+			ref.Controller = ptrToBool(v)
+		}
+		if v, ok := m["block_owner_deletion"].(bool); ok && v {
+			ref.BlockOwnerDeletion = ptrToBool(v)
+		}
+		refs = append(refs, ref)
+	}
+	return refs
+}
+
+// FlattenOwnerReferences flattens k8s owner references present in configuration into terraform list,
+// matching them by kind and name. There may be some other owner references added by controllers.
+// UIDs are kept only where they are set in configuration, others are resolved at apply time.
+func FlattenOwnerReferences(refs []metav1.OwnerReference, d *schema.ResourceData, key string) []interface{} {
+	config, _ := d.Get(key).([]interface{})
+	out := make([]interface{}, 0, len(config))
+	for _, c := range ExpandOwnerReferences(config) {
+		i := indexOfOwnerReference(refs, c)
+		if i < 0 {
+			continue
+		}
+		ref := refs[i]
+		m := make(map[string]interface{})
+		m["api_version"] = ref.APIVersion
+		m["kind"] = ref.Kind
+		m["name"] = ref.Name
+		m["uid"] = ""
+		if c.UID != "" {
+			m["uid"] = string(ref.UID)
+		}
+		m["controller"] = ref.Controller != nil && *ref.Controller
+		m["block_owner_deletion"] = ref.BlockOwnerDeletion != nil && *ref.BlockOwnerDeletion
+		out = append(out, m)
+	}
+	return out
+}
+
+// diffOwnerReferences produces patch operations adding, updating and removing owner references managed by TF.
+// There may be some other owner references added by controllers and we don't want to touch these.
+// Values of produced operations are *metav1.OwnerReference or []metav1.OwnerReference,
+// whose missing UIDs are set by ResolvePatchOwnerReferences.
+func diffOwnerReferences(path string, current []metav1.OwnerReference, oldV, newV []interface{}) PatchOperations {
+	listPath := parseJSONPointerPrefix(path)
+	oldRefs := ExpandOwnerReferences(oldV)
+	newRefs := ExpandOwnerReferences(newV)
+	tests := make(map[string]interface{})
+	ops := make([]PatchOperation, 0, 0)
+	testItem := func(i int) JSONPointer {
+		itemPath := listPath.Append(strconv.Itoa(i))
+		tests[itemPath.Append("kind").String()] = current[i].Kind
+		tests[itemPath.Append("name").String()] = current[i].Name
+		return itemPath
+	}
+
+	// Updates go first, as removals shift indices
+	add := make([]metav1.OwnerReference, 0, 0)
+	for _, ref := range newRefs {
+		i := indexOfOwnerReference(current, ref)
+		if i < 0 {
+			add = append(add, ref)
+			continue
+		}
+		if ref.UID == "" {
+			ref.UID = current[i].UID
+		}
+		if ownerReferencesEqual(ref, current[i]) {
+			continue
+		}
+		value := ref
+		ops = append(ops, &ReplaceOperation{
+			Path:  testItem(i).String(),
+			Value: &value,
+		})
+	}
+
+	remove := make([]int, 0, 0)
+	for _, ref := range oldRefs {
+		if indexOfOwnerReference(newRefs, ref) >= 0 {
+			continue
+		}
+		if i := indexOfOwnerReference(current, ref); i >= 0 {
+			remove = append(remove, i)
+		}
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(remove)))
+	for _, i := range remove {
+		ops = append(ops, &RemoveOperation{
+			Path: testItem(i).String(),
+		})
+	}
+
+	if len(add) > 0 && len(current) == 0 {
+		ops = append(ops, &AddOperation{
+			Path:  listPath.String(),
+			Value: add,
+		})
+	} else {
+		for i := range add {
+			ops = append(ops, &AddOperation{
+				Path:  listPath.Append("-").String(),
+				Value: &add[i],
+			})
+		}
+	}
+	if len(tests) == 0 {
+		return ops
+	}
+	return PrependFieldTests(ops, tests)
+}
+
+func indexOfOwnerReference(refs []metav1.OwnerReference, ref metav1.OwnerReference) int {
+	for i, r := range refs {
+		if r.Kind == ref.Kind && r.Name == ref.Name {
+			return i
+		}
+	}
+	return -1
+}
+
+func ownerReferencesEqual(a, b metav1.OwnerReference) bool {
+	return a.APIVersion == b.APIVersion && a.Kind == b.Kind && a.Name == b.Name && a.UID == b.UID &&
+		(a.Controller != nil && *a.Controller) == (b.Controller != nil && *b.Controller) &&
+		(a.BlockOwnerDeletion != nil && *a.BlockOwnerDeletion) == (b.BlockOwnerDeletion != nil && *b.BlockOwnerDeletion)
+}
+
+// managedFinalizers filters out finalizers not present in configuration
+func managedFinalizers(finalizers []string, config *schema.Set) []interface{} {
+	out := make([]interface{}, 0, 0)
+	if config == nil {
+		return out
+	}
+	for _, f := range finalizers {
+		if config.Contains(f) {
+			out = append(out, f)
+		}
+	}
+	return out
+}
+
+func isStringInSlice(s string, list []string) bool {
+	for _, v := range list {
+		if v == s {
+			return true
+		}
+	}
+	return false
+}
+
 func removeInternalKeys(m map[string]string, d map[string]interface{}) map[string]string {
 	for k := range m {
 		if isInternalKey(k) && !isKeyInMap(k, d) {
@@ -0,0 +1,126 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"testing"
+
+	"github.com/hashicorp/terraform-plugin-sdk/helper/schema"
+	"github.com/hashicorp/terraform-plugin-sdk/terraform"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestPatchMetadataKeepsUnmanagedItems(t *testing.T) {
+	resourceSchema := map[string]*schema.Schema{
+		"metadata": NamespacedMetadataSchema("config map", false),
+	}
+	state := &terraform.InstanceState{
+		ID: "ns/cm",
+		Attributes: map[string]string{
+			"id":                      "ns/cm",
+			"metadata.#":              "1",
+			"metadata.0.name":         "cm",
+			"metadata.0.namespace":    "ns",
+			"metadata.0.finalizers.#": "1",
+			fmt.Sprintf("metadata.0.finalizers.%d", schema.HashString("example.com/old")): "example.com/old",
+			"metadata.0.owner_references.#":                                               "1",
+			"metadata.0.owner_references.0.api_version":                                   "v1",
+			"metadata.0.owner_references.0.kind":                                          "ConfigMap",
+			"metadata.0.owner_references.0.name":                                          "old-owner",
+			"metadata.0.owner_references.0.uid":                                           "",
+			"metadata.0.owner_references.0.controller":                                    "false",
+			"metadata.0.owner_references.0.block_owner_deletion":                          "false",
+		},
+	}
+	config := terraform.NewResourceConfigRaw(map[string]interface{}{
+		"metadata": []interface{}{map[string]interface{}{
+			"name":       "cm",
+			"namespace":  "ns",
+			"finalizers": []interface{}{"example.com/new"},
+			"owner_references": []interface{}{map[string]interface{}{
+				"api_version": "v1",
+				"kind":        "ConfigMap",
+				"name":        "new-owner",
+			}},
+		}},
+	})
+	diff, err := schema.InternalMap(resourceSchema).Diff(state, config, nil, nil, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	d, err := schema.InternalMap(resourceSchema).Data(state, diff)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	current := &api.ConfigMap{
+		TypeMeta: metav1.TypeMeta{Kind: "ConfigMap", APIVersion: "v1"},
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace:  "ns",
+			Name:       "cm",
+			Finalizers: []string{"controller.example.com/protect", "example.com/old"},
+			OwnerReferences: []metav1.OwnerReference{
+				{APIVersion: "v1", Kind: "ConfigMap", Name: "old-owner", UID: "old-uid"},
+				{APIVersion: "example.com/v1", Kind: "Operator", Name: "op", UID: "op-uid"},
+			},
+		},
+	}
+
+	if _, err := PatchMetadataWithObject("metadata.0.", "/metadata/", d, nil); err == nil {
+		t.Error("Expected error patching finalizers without current object")
+	}
+	if _, err := PatchMetadataWithObject("metadata.0.", "/metadata/", d, (*api.ConfigMap)(nil)); err == nil {
+		t.Error("Expected error patching finalizers with typed nil object")
+	}
+	if ops := PatchMetadata("metadata.0.", "/metadata/", d); len(ops) != 0 {
+		t.Errorf("Expected finalizers and owner references to be skipped, got %v", ops)
+	}
+
+	ops, err := PatchMetadataWithObject("metadata.0.", "/metadata/", d, current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	owner := &api.ConfigMap{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "new-owner", UID: "new-uid"}}
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme, owner)
+	if err := ResolvePatchOwnerReferences(context.TODO(), conn, "ns", ops); err != nil {
+		t.Fatal(err)
+	}
+
+	doc, err := json.Marshal(current)
+	if err != nil {
+		t.Fatal(err)
+	}
+	patched, err := ops.Apply(doc)
+	if err != nil {
+		t.Fatalf("Failed to apply %v: %s", ops, err)
+	}
+	result := api.ConfigMap{}
+	if err := json.Unmarshal(patched, &result); err != nil {
+		t.Fatal(err)
+	}
+
+	expectedFinalizers := []string{"controller.example.com/protect", "example.com/new"}
+	if !reflect.DeepEqual(result.Finalizers, expectedFinalizers) {
+		t.Errorf("Expected finalizers %q, got %q", expectedFinalizers, result.Finalizers)
+	}
+	expectedRefs := []metav1.OwnerReference{
+		{APIVersion: "example.com/v1", Kind: "Operator", Name: "op", UID: "op-uid"},
+		{APIVersion: "v1", Kind: "ConfigMap", Name: "new-owner", UID: "new-uid"},
+	}
+	if !reflect.DeepEqual(result.OwnerReferences, expectedRefs) {
+		t.Errorf("Expected owner references %v, got %v", expectedRefs, result.OwnerReferences)
+	}
+
+	flattened := FlattenMetadata(result.ObjectMeta, d)[0].(map[string]interface{})
+	if refs := flattened["owner_references"].([]interface{}); len(refs) != 1 || refs[0].(map[string]interface{})["name"] != "new-owner" {
+		t.Errorf("Expected only managed owner reference to be flattened, got %v", refs)
+	}
+	if finalizers := flattened["finalizers"].([]interface{}); len(finalizers) != 1 || finalizers[0] != "example.com/new" {
+		t.Errorf("Expected only managed finalizer to be flattened, got %v", finalizers)
+	}
+}
@@ -0,0 +1,60 @@
+package kubernetes
+
+import (
+	"time"
+
+	api "k8s.io/api/core/v1"
+)
+
+// ExpandWaitFor converts terraform wait_for block to WaitSpec, nil when block is empty
+func ExpandWaitFor(in []interface{}) (*WaitSpec, error) {
+	if len(in) < 1 || in[0] == nil {
+		return nil, nil
+	}
+	m := in[0].(map[string]interface{})
+	spec := &WaitSpec{}
+
+	if v, ok := m["condition"].([]interface{}); ok {
+		for _, c := range v {
+			cm := c.(map[string]interface{})
+			condition := ConditionSpec{
+				Type:   cm["type"].(string),
+				Status: api.ConditionStatus(cm["status"].(string)),
+			}
+			if reasons, ok := cm["terminal_reasons"].([]interface{}); ok {
+				condition.TerminalReasons = expandStringSlice(reasons)
+			}
+			spec.Conditions = append(spec.Conditions, condition)
+		}
+	}
+
+	if v, ok := m["field"].([]interface{}); ok {
+		for _, f := range v {
+			fm := f.(map[string]interface{})
+			path, err := ParseFieldPath(fm["path"].(string))
+			if err != nil {
+				return nil, err
+			}
+			spec.Fields = append(spec.Fields, FieldSpec{
+				Path:  path,
+				Value: fm["value"].(string),
+			})
+		}
+	}
+
+	if v, ok := m["rollout"].(bool); ok {
+		spec.Rollout = v
+	}
+
+	timeout := defaultWaitForTimeout
+	if v, ok := m["timeout"].(string); ok && v != "" {
+		timeout = v
+	}
+	d, err := time.ParseDuration(timeout)
+	if err != nil {
+		return nil, err
+	}
+	spec.Timeout = d
+
+	return spec, nil
+}
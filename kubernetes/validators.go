@@ -3,6 +3,7 @@ package kubernetes
 import (
 	"fmt"
 	"strings"
+	"time"
 
 	"k8s.io/apimachinery/pkg/api/resource"
 	apiValidation "k8s.io/apimachinery/pkg/api/validation"
@@ -77,3 +78,50 @@ func ValidateResourceQuantity(value interface{}, key string) (ws []string, es []
 	}
 	return
 }
+
+// ValidateDuration validates the string is valid duration
+func ValidateDuration(value interface{}, key string) (ws []string, es []error) {
+	v := value.(string)
+	if _, err := time.ParseDuration(v); err != nil {
+		es = append(es, fmt.Errorf("%s (%q) %s", key, v, err))
+	}
+	return
+}
+
+// ValidateFieldPath validates the string is valid JSONPath-like field path
+func ValidateFieldPath(value interface{}, key string) (ws []string, es []error) {
+	v := value.(string)
+	if _, err := ParseFieldPath(v); err != nil {
+		es = append(es, fmt.Errorf("%s (%q) %s", key, v, err))
+	}
+	return
+}
+
+// ValidateConditionStatus validates the string is valid status of a condition
+func ValidateConditionStatus(value interface{}, key string) (ws []string, es []error) {
+	v := value.(string)
+	switch v {
+	case "True", "False", "Unknown":
+	default:
+		es = append(es, fmt.Errorf("%s (%q) must be one of True, False, Unknown", key, v))
+	}
+	return
+}
+
+// ValidateJSONPatch validates the string is valid JSON patch document
+func ValidateJSONPatch(value interface{}, key string) (ws []string, es []error) {
+	v := value.(string)
+	if _, err := ParsePatch([]byte(v)); err != nil {
+		es = append(es, fmt.Errorf("%s %s", key, err))
+	}
+	return
+}
+
+// ValidateFinalizer validates finalizer name
+func ValidateFinalizer(value interface{}, key string) (ws []string, es []error) {
+	v := value.(string)
+	for _, msg := range utilValidation.IsQualifiedName(v) {
+		es = append(es, fmt.Errorf("%s (%q) %s", key, v, msg))
+	}
+	return
+}
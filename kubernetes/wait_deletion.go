@@ -0,0 +1,129 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// DeletionOptions customizes WaitForDeletion
+type DeletionOptions struct {
+	// StripFinalizers is explicit opt-in to remove listed finalizers
+	// from the object which is still present when timeout is reached
+	StripFinalizers []string
+	// StripTimeout is how long to wait for deletion after finalizers are stripped
+	StripTimeout time.Duration
+}
+
+// WaitForDeletion waits until object is gone, reporting finalizers blocking the deletion on timeout
+func WaitForDeletion(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, timeout time.Duration, opts DeletionOptions) error {
+	obj, err := waitForDeletion(ctx, conn, key, gvk, timeout)
+	if err != nil || obj == nil {
+		return err
+	}
+
+	if len(opts.StripFinalizers) > 0 {
+		ops := RemoveFinalizersOperations(obj.GetFinalizers(), opts.StripFinalizers)
+		if len(ops) > 0 {
+			log.Printf("[WARN] Stripping finalizers %q from %s %s", opts.StripFinalizers, gvk.Kind, key)
+			_, err = PatchObject(ctx, conn, obj, ops)
+			if err != nil && !apierrors.IsNotFound(err) {
+				return fmt.Errorf("Failed to strip finalizers from %s %s: %s", gvk.Kind, key, err)
+			}
+
+			stripTimeout := opts.StripTimeout
+			if stripTimeout == 0 {
+				stripTimeout = time.Minute
+			}
+			obj, err = waitForDeletion(ctx, conn, key, gvk, stripTimeout)
+			if err != nil || obj == nil {
+				return err
+			}
+		}
+	}
+
+	return withLastWarnings(conn, metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}, gvk.Kind,
+		fmt.Errorf("timeout while waiting for %s %s to be deleted: %s", gvk.Kind, key, describeDeletion(obj)))
+}
+
+// RemoveFinalizersOperations produces patch operations removing given finalizers.
+// Removed items are tested first, so the patch fails if finalizers were changed meanwhile,
+// and are removed from the end, so indices stay valid while the patch is applied.
+func RemoveFinalizersOperations(finalizers []string, remove []string) PatchOperations {
+	return removeFinalizersOperations(NewJSONPointer("metadata", "finalizers"), finalizers, remove)
+}
+
+// removeFinalizersOperations produces operations removing finalizers from list at given path
+func removeFinalizersOperations(listPath JSONPointer, finalizers []string, remove []string) PatchOperations {
+	tests := make(map[string]interface{})
+	ops := make([]PatchOperation, 0, 0)
+	for i := len(finalizers) - 1; i >= 0; i-- {
+		for _, r := range remove {
+			if finalizers[i] != r {
+				continue
+			}
+			path := listPath.Append(strconv.Itoa(i)).String()
+			tests[path] = r
+			ops = append(ops, &RemoveOperation{
+				Path: path,
+			})
+			break
+		}
+	}
+	if len(ops) == 0 {
+		return ops
+	}
+	return PrependFieldTests(ops, tests)
+}
+
+// waitForDeletion waits until object is gone, returning last seen object when timeout is reached
+func waitForDeletion(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, timeout time.Duration) (*unstructured.Unstructured, error) {
+	var last *unstructured.Unstructured
+	err := waitForState(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			return true, nil
+		}
+		last = obj
+		log.Printf("[DEBUG] Waiting for %s %s to be deleted: %s", gvk.Kind, key, describeDeletion(obj))
+		return false, nil
+	})
+	if err == errWaitTimeout {
+		return last, nil
+	}
+	return nil, err
+}
+
+// describeDeletion describes what is blocking deletion of the object
+func describeDeletion(obj *unstructured.Unstructured) string {
+	var parts []string
+	if ts := obj.GetDeletionTimestamp(); ts != nil {
+		parts = append(parts, fmt.Sprintf("deletion requested at %s", ts.UTC().Format(time.RFC3339)))
+	} else {
+		parts = append(parts, "deletion not requested yet")
+	}
+	if finalizers := obj.GetFinalizers(); len(finalizers) > 0 {
+		parts = append(parts, fmt.Sprintf("remaining finalizers: %s", strings.Join(finalizers, ", ")))
+	}
+
+	if obj.GetKind() == "Namespace" {
+		for _, t := range []api.NamespaceConditionType{api.NamespaceContentRemaining, api.NamespaceFinalizersRemaining} {
+			c, found, err := findCondition(obj, string(t))
+			if err != nil || !found || c["status"] != string(api.ConditionTrue) {
+				continue
+			}
+			parts = append(parts, fmt.Sprintf("%s: %s", t, c["message"]))
+		}
+	}
+
+	return strings.Join(parts, "; ")
+}
@@ -0,0 +1,227 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaitBackoff is the backoff used by wait helpers between object checks
+var WaitBackoff = wait.Backoff{
+	Duration: 1 * time.Second,
+	Factor:   1.5,
+	Jitter:   0.1,
+	Steps:    10,
+	Cap:      15 * time.Second,
+}
+
+// WaitWarningsLimit is number of warnings attached to wait errors
+var WaitWarningsLimit = 5
+
+// errWaitTimeout is returned by pollWithBackoff when timeout is reached
+var errWaitTimeout = errors.New("timeout")
+
+// ConditionSpec describes status condition to wait for
+type ConditionSpec struct {
+	// Type of the condition, e.g. Ready
+	Type string
+	// Status expected, defaults to True
+	Status api.ConditionStatus
+	// TerminalReasons stop the wait early when condition
+	// has status False with one of these reasons
+	TerminalReasons []string
+}
+
+// objectCheck inspects the object and reports if wait is done,
+// describing current state for timeout errors. Returned error stops the wait.
+type objectCheck func(obj *unstructured.Unstructured) (done bool, state string, err error)
+
+// WaitForCondition waits until status condition of the object reaches expected status
+func WaitForCondition(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, condition ConditionSpec, timeout time.Duration) error {
+	return waitForObject(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, string, error) {
+		return condition.Check(obj)
+	})
+}
+
+// Check reports if object's condition has expected status and describes the condition
+func (condition ConditionSpec) Check(obj *unstructured.Unstructured) (bool, string, error) {
+	expected := condition.Status
+	if expected == "" {
+		expected = api.ConditionTrue
+	}
+
+	c, found, err := findCondition(obj, condition.Type)
+	if err != nil {
+		return false, "", err
+	}
+	if !found {
+		return false, fmt.Sprintf("condition %s is not reported yet", condition.Type), nil
+	}
+
+	status := api.ConditionStatus(c["status"])
+	if status == expected {
+		return true, "", nil
+	}
+	if status == api.ConditionFalse {
+		for _, r := range condition.TerminalReasons {
+			if c["reason"] == r {
+				return false, "", fmt.Errorf("condition %s is %s%s",
+					condition.Type, status, describeCondition(c))
+			}
+		}
+	}
+	return false, fmt.Sprintf("condition %s is %s (expected %s)%s",
+		condition.Type, status, expected, describeCondition(c)), nil
+}
+
+// findCondition looks up status condition of given type in unstructured object
+func findCondition(obj *unstructured.Unstructured, conditionType string) (map[string]string, bool, error) {
+	conditions, found, err := unstructured.NestedSlice(obj.Object, "status", "conditions")
+	if err != nil || !found {
+		return nil, false, err
+	}
+	for _, c := range conditions {
+		m, ok := c.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		if m["type"] != conditionType {
+			continue
+		}
+		out := make(map[string]string, len(m))
+		for k, v := range m {
+			if s, ok := v.(string); ok {
+				out[k] = s
+			}
+		}
+		return out, true, nil
+	}
+	return nil, false, nil
+}
+
+// describeCondition formats condition reason and message
+func describeCondition(c map[string]string) string {
+	out := ""
+	if c["reason"] != "" {
+		out += ": " + c["reason"]
+	}
+	if c["message"] != "" {
+		out += ": " + c["message"]
+	}
+	return out
+}
+
+// waitForObject waits until check reports the object is done, fails or timeout is reached.
+// Errors are extended with latest warnings reported for the object.
+func waitForObject(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, timeout time.Duration, check objectCheck) error {
+	state := "object not found"
+	err := waitForState(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			log.Printf("[DEBUG] %s %s not found yet", gvk.Kind, key)
+			return false, nil
+		}
+
+		done, s, err := check(obj)
+		if err != nil {
+			return false, err
+		}
+		state = s
+		if !done {
+			log.Printf("[DEBUG] Waiting for %s %s: %s", gvk.Kind, key, state)
+		}
+		return done, nil
+	})
+	if err == nil {
+		return nil
+	}
+	// Cancellation is returned as is, so callers can tell it apart
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	if err == errWaitTimeout {
+		err = fmt.Errorf("timeout while waiting for %s %s: %s", gvk.Kind, key, state)
+	} else {
+		err = fmt.Errorf("%s %s failed: %s", gvk.Kind, key, err)
+	}
+
+	return withLastWarnings(conn, metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name}, gvk.Kind, err)
+}
+
+// waitForState calls cond with current state of the object, which is nil when object
+// doesn't exist, until it's done, fails, context is cancelled or timeout is reached.
+// Object is watched when conn is WatchingClient and polled otherwise.
+func waitForState(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, timeout time.Duration, cond func(obj *unstructured.Unstructured) (bool, error)) error {
+	if wc, ok := conn.(*WatchingClient); ok {
+		start := time.Now()
+		err := wc.watcher.waitFor(ctx, gvk, key, timeout, cond)
+		if err != errWatchUnavailable {
+			return err
+		}
+		// Polling gets only the time left
+		timeout -= time.Since(start)
+	}
+
+	return pollWithBackoff(ctx, timeout, func() (bool, error) {
+		obj := &unstructured.Unstructured{}
+		obj.SetGroupVersionKind(gvk)
+		err := conn.Get(ctx, key, obj)
+		if err != nil {
+			if !apierrors.IsNotFound(err) {
+				return false, err
+			}
+			obj = nil
+		}
+		return cond(obj)
+	})
+}
+
+// pollWithBackoff calls condition until it's done, fails, context is cancelled
+// or timeout is reached in which case errWaitTimeout is returned
+func pollWithBackoff(ctx context.Context, timeout time.Duration, condition wait.ConditionFunc) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	backoff := WaitBackoff
+	for {
+		done, err := condition()
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errWaitTimeout
+		case <-time.After(backoff.Step()):
+		}
+	}
+}
+
+// withLastWarnings appends latest warnings for the object to the error
+func withLastWarnings(conn client.Client, metadata metav1.ObjectMeta, kind string, err error) error {
+	warnings, wErr := GetLastWarningsForObject(conn, metadata, kind, WaitWarningsLimit)
+	if wErr != nil {
+		log.Printf("[WARN] Failed to look up warnings for %s/%s (%s): %s",
+			metadata.Namespace, metadata.Name, kind, wErr)
+		return err
+	}
+	if len(warnings) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s%s", err, StringifyEvents(warnings))
+}
@@ -0,0 +1,108 @@
+package kubernetes
+
+import (
+	"context"
+	"strings"
+	"testing"
+	"time"
+
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testWidgetGVK = apimachineryschema.GroupVersionKind{Group: "example.com", Version: "v1", Kind: "Widget"}
+
+func newTestWidget(conditions ...map[string]interface{}) *unstructured.Unstructured {
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(testWidgetGVK)
+	obj.SetNamespace("ns")
+	obj.SetName("widget")
+	list := make([]interface{}, 0, len(conditions))
+	for _, c := range conditions {
+		list = append(list, c)
+	}
+	unstructured.SetNestedSlice(obj.Object, list, "status", "conditions")
+	return obj
+}
+
+func TestWaitForCondition(t *testing.T) {
+	key := client.ObjectKey{Namespace: "ns", Name: "widget"}
+	condition := ConditionSpec{Type: "Ready", TerminalReasons: []string{"InvalidSpec"}}
+
+	cases := []struct {
+		name    string
+		obj     *unstructured.Unstructured
+		timeout time.Duration
+		err     string
+	}{
+		{
+			name: "ready",
+			obj:  newTestWidget(map[string]interface{}{"type": "Ready", "status": "True"}),
+		},
+		{
+			name: "terminal reason",
+			obj: newTestWidget(map[string]interface{}{
+				"type": "Ready", "status": "False", "reason": "InvalidSpec", "message": "size must be positive",
+			}),
+			err: "Widget ns/widget failed: condition Ready is False: InvalidSpec: size must be positive",
+		},
+		{
+			name: "timeout",
+			obj: newTestWidget(map[string]interface{}{
+				"type": "Ready", "status": "False", "reason": "Provisioning",
+			}),
+			timeout: 10 * time.Millisecond,
+			err:     "timeout while waiting for Widget ns/widget: condition Ready is False (expected True): Provisioning",
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			conn := fake.NewFakeClientWithScheme(scheme.Scheme, c.obj)
+			timeout := c.timeout
+			if timeout == 0 {
+				timeout = time.Minute
+			}
+			err := WaitForCondition(context.TODO(), conn, key, testWidgetGVK, condition, timeout)
+			if c.err == "" && err != nil {
+				t.Fatalf("Unexpected error: %s", err)
+			}
+			if c.err != "" && (err == nil || !strings.HasPrefix(err.Error(), c.err)) {
+				t.Fatalf("Expected error %q, got %v", c.err, err)
+			}
+		})
+	}
+}
+
+func TestWaitForConditionCancelled(t *testing.T) {
+	// Warnings must not be attached to cancellation
+	warning := &api.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "widget.1"},
+		InvolvedObject: api.ObjectReference{Kind: "Widget", Namespace: "ns", Name: "widget"},
+		Type:           api.EventTypeWarning,
+		Reason:         "Stuck",
+		Message:        "provisioning is stuck",
+	}
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme, newTestWidget(), warning)
+	key := client.ObjectKey{Namespace: "ns", Name: "widget"}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+
+	start := time.Now()
+	err := WaitForCondition(ctx, conn, key, testWidgetGVK, ConditionSpec{Type: "Ready"}, time.Minute)
+	if err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+	if elapsed := time.Since(start); elapsed > 5*time.Second {
+		t.Errorf("Wait wasn't stopped by cancellation, took %s", elapsed)
+	}
+}
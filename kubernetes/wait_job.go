@@ -0,0 +1,123 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sort"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	corev1client "k8s.io/client-go/kubernetes/typed/core/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// defaultJobBackoffLimit is backoffLimit k8s uses when none is specified
+const defaultJobBackoffLimit = 6
+
+// WaitForJob waits until job completes. When job fails or times out, the error includes
+// last logLines lines of failed pod's containers (if pods getter is given) and pod warnings.
+func WaitForJob(ctx context.Context, conn client.Client, pods corev1client.PodsGetter, key client.ObjectKey, timeout time.Duration, logLines int64) error {
+	gvk := batchv1.SchemeGroupVersion.WithKind("Job")
+	job := batchv1.Job{}
+	err := waitForObject(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, string, error) {
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &job); err != nil {
+			return false, "", err
+		}
+		return JobCompletionStatus(&job)
+	})
+	// Cancellation is returned as is, so callers can tell it apart
+	if err == nil || ctx.Err() != nil {
+		return err
+	}
+
+	pod, pErr := lastFailedJobPod(conn, &job)
+	if pErr != nil {
+		log.Printf("[WARN] Failed to look up failed pods of job %s: %s", key, pErr)
+		return err
+	}
+	if pod == nil {
+		return err
+	}
+
+	err = withLastWarnings(conn, pod.ObjectMeta, "Pod", err)
+	if pods == nil || logLines <= 0 {
+		return err
+	}
+
+	logs := getPodLogs(context.TODO(), pods, pod, logLines)
+	if len(logs) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s%s", err, StringifyContainerLogs(logs))
+}
+
+// JobCompletionStatus reports if job is complete and describes its progress
+func JobCompletionStatus(job *batchv1.Job) (bool, string, error) {
+	for _, c := range job.Status.Conditions {
+		if c.Status != api.ConditionTrue {
+			continue
+		}
+		switch c.Type {
+		case batchv1.JobComplete:
+			return true, "", nil
+		case batchv1.JobFailed:
+			return false, "", fmt.Errorf("job failed: %s: %s", c.Reason, c.Message)
+		}
+	}
+
+	completions := int32(1)
+	if job.Spec.Completions != nil {
+		completions = *job.Spec.Completions
+	}
+	backoffLimit := int32(defaultJobBackoffLimit)
+	if job.Spec.BackoffLimit != nil {
+		backoffLimit = *job.Spec.BackoffLimit
+	}
+
+	if job.Status.Succeeded >= completions {
+		return true, "", nil
+	}
+	if job.Status.Failed > backoffLimit {
+		return false, "", fmt.Errorf("job failed: %d pods failed, backoff limit is %d",
+			job.Status.Failed, backoffLimit)
+	}
+	return false, fmt.Sprintf("%d of %d completions succeeded, %d failed (backoff limit %d)",
+		job.Status.Succeeded, completions, job.Status.Failed, backoffLimit), nil
+}
+
+// lastFailedJobPod returns the most recently started failed pod of the job
+func lastFailedJobPod(conn client.Client, job *batchv1.Job) (*api.Pod, error) {
+	if job.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(job.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := api.PodList{}
+	err = conn.List(context.TODO(), &pods, client.InNamespace(job.Namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var failed []api.Pod
+	for _, pod := range pods.Items {
+		if pod.Status.Phase == api.PodFailed || ClassifyPodHealth(&pod).State == PodFailing {
+			failed = append(failed, pod)
+		}
+	}
+	if len(failed) == 0 {
+		return nil, nil
+	}
+
+	sort.Slice(failed, func(i, j int) bool {
+		return failed[i].CreationTimestamp.After(failed[j].CreationTimestamp.Time)
+	})
+	return &failed[0], nil
+}
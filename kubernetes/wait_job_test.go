@@ -0,0 +1,118 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	batchv1 "k8s.io/api/batch/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func TestWaitForJobFailureIncludesLogs(t *testing.T) {
+	var logRequests []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v1/namespaces/ns/pods/migrate-abcde/log" {
+			http.NotFound(w, r)
+			return
+		}
+		logRequests = append(logRequests, r.URL.RawQuery)
+		q := r.URL.Query()
+		if q.Get("container") != "main" || q.Get("tailLines") != "2" {
+			w.WriteHeader(http.StatusBadRequest)
+			return
+		}
+		fmt.Fprint(w, "connecting to database\nERROR: relation \"users\" does not exist\n")
+	}))
+	defer srv.Close()
+	clientset, err := kubernetes.NewForConfig(&rest.Config{Host: srv.URL})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	backoffLimit := int32(0)
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "migrate"},
+		Spec: batchv1.JobSpec{
+			BackoffLimit: &backoffLimit,
+			Selector:     &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "migrate"}},
+		},
+		Status: batchv1.JobStatus{
+			Failed: 1,
+			Conditions: []batchv1.JobCondition{{
+				Type:    batchv1.JobFailed,
+				Status:  api.ConditionTrue,
+				Reason:  "BackoffLimitExceeded",
+				Message: "Job has reached the specified backoff limit",
+			}},
+		},
+	}
+	pod := &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "migrate-abcde", Labels: map[string]string{"job-name": "migrate"}},
+		Status: api.PodStatus{
+			Phase: api.PodFailed,
+			ContainerStatuses: []api.ContainerStatus{{
+				Name:  "main",
+				State: api.ContainerState{Terminated: &api.ContainerStateTerminated{ExitCode: 1, Reason: "Error"}},
+			}},
+		},
+	}
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme, job, pod)
+
+	err = WaitForJob(context.TODO(), conn, clientset.CoreV1(), client.ObjectKey{Namespace: "ns", Name: "migrate"}, time.Minute, 2)
+	if err == nil {
+		t.Fatal("Expected job failure")
+	}
+	expected := "Job ns/migrate failed: job failed: BackoffLimitExceeded: Job has reached the specified backoff limit" +
+		"\n   * migrate-abcde (main): last 2 log lines:" +
+		"\n       connecting to database" +
+		"\n       ERROR: relation \"users\" does not exist"
+	if err.Error() != expected {
+		t.Errorf("Expected error:\n%s\ngot:\n%s", expected, err)
+	}
+	if len(logRequests) != 1 || strings.Contains(logRequests[0], "previous=true") {
+		t.Errorf("Expected single request of current logs, got %v", logRequests)
+	}
+}
+
+func TestWaitForJobCancelled(t *testing.T) {
+	job := &batchv1.Job{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "migrate"},
+		Spec: batchv1.JobSpec{
+			Selector: &metav1.LabelSelector{MatchLabels: map[string]string{"job-name": "migrate"}},
+		},
+		Status: batchv1.JobStatus{Failed: 1},
+	}
+	pod := &api.Pod{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "migrate-abcde", Labels: map[string]string{"job-name": "migrate"}},
+		Status:     api.PodStatus{Phase: api.PodFailed},
+	}
+	warning := &api.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "migrate-abcde.1"},
+		InvolvedObject: api.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "migrate-abcde"},
+		Type:           api.EventTypeWarning,
+		Reason:         "BackOff",
+		Message:        "Back-off restarting failed container",
+	}
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme, job, pod, warning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err := WaitForJob(ctx, conn, nil, client.ObjectKey{Namespace: "ns", Name: "migrate"}, time.Minute, 10)
+	if err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+}
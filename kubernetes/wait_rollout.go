@@ -0,0 +1,338 @@
+package kubernetes
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// deploymentRevisionAnnotation holds revision of deployment and its replica sets
+const deploymentRevisionAnnotation = "deployment.kubernetes.io/revision"
+
+// RolloutPodsLimit is max number of not ready pods whose warnings are attached to rollout errors
+var RolloutPodsLimit = 3
+
+// WaitForDeploymentRollout waits until deployment is rolled out, like `kubectl rollout status`
+func WaitForDeploymentRollout(ctx context.Context, conn client.Client, key client.ObjectKey, timeout time.Duration) error {
+	return waitForRollout(ctx, conn, key, appsv1.SchemeGroupVersion.WithKind("Deployment"), timeout)
+}
+
+// WaitForStatefulSetRollout waits until stateful set is rolled out, like `kubectl rollout status`
+func WaitForStatefulSetRollout(ctx context.Context, conn client.Client, key client.ObjectKey, timeout time.Duration) error {
+	return waitForRollout(ctx, conn, key, appsv1.SchemeGroupVersion.WithKind("StatefulSet"), timeout)
+}
+
+// WaitForDaemonSetRollout waits until daemon set is rolled out, like `kubectl rollout status`
+func WaitForDaemonSetRollout(ctx context.Context, conn client.Client, key client.ObjectKey, timeout time.Duration) error {
+	return waitForRollout(ctx, conn, key, appsv1.SchemeGroupVersion.WithKind("DaemonSet"), timeout)
+}
+
+func waitForRollout(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, timeout time.Duration) error {
+	err := waitForObject(ctx, conn, key, gvk, timeout, func(obj *unstructured.Unstructured) (bool, string, error) {
+		return RolloutStatus(conn, obj)
+	})
+	if err == nil {
+		return nil
+	}
+	return withRolloutPodWarnings(conn, key, gvk, err)
+}
+
+// RolloutStatus reports if Deployment, StatefulSet or DaemonSet is rolled out and describes its progress.
+// Rollout fails early when one of workload's pods of the current revision is terminally failing,
+// pods of previous revisions are about to be replaced and are ignored.
+func RolloutStatus(conn client.Client, obj *unstructured.Unstructured) (bool, string, error) {
+	var done bool
+	var state string
+	var err error
+	var selector *metav1.LabelSelector
+
+	switch obj.GetKind() {
+	case "Deployment":
+		d := appsv1.Deployment{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &d); err != nil {
+			return false, "", err
+		}
+		done, state, err = DeploymentRolloutStatus(&d)
+		if !done && err == nil {
+			selector, err = deploymentRevisionSelector(conn, &d)
+		}
+	case "StatefulSet":
+		s := appsv1.StatefulSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &s); err != nil {
+			return false, "", err
+		}
+		done, state, err = StatefulSetRolloutStatus(&s)
+		if s.Status.UpdateRevision != "" {
+			selector = withRevisionLabel(s.Spec.Selector, appsv1.StatefulSetRevisionLabel, s.Status.UpdateRevision)
+		}
+	case "DaemonSet":
+		ds := appsv1.DaemonSet{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, &ds); err != nil {
+			return false, "", err
+		}
+		done, state, err = DaemonSetRolloutStatus(&ds)
+		if !done && err == nil {
+			selector, err = daemonSetRevisionSelector(conn, &ds)
+		}
+	default:
+		return false, "", fmt.Errorf("rollout status is not supported for %s", obj.GetKind())
+	}
+
+	if done || err != nil {
+		return done, state, err
+	}
+	return false, state, checkPodsFailing(conn, obj.GetNamespace(), selector)
+}
+
+// deploymentRevisionSelector selects pods of deployment's new replica set,
+// returning nil when the new replica set isn't created yet
+func deploymentRevisionSelector(conn client.Client, d *appsv1.Deployment) (*metav1.LabelSelector, error) {
+	revision := d.Annotations[deploymentRevisionAnnotation]
+	if revision == "" || d.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(d.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	replicaSets := appsv1.ReplicaSetList{}
+	err = conn.List(context.TODO(), &replicaSets, client.InNamespace(d.Namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+	for _, rs := range replicaSets.Items {
+		if !metav1.IsControlledBy(&rs, d) || rs.Annotations[deploymentRevisionAnnotation] != revision {
+			continue
+		}
+		if hash, ok := rs.Labels[appsv1.DefaultDeploymentUniqueLabelKey]; ok {
+			return withRevisionLabel(d.Spec.Selector, appsv1.DefaultDeploymentUniqueLabelKey, hash), nil
+		}
+	}
+	return nil, nil
+}
+
+// daemonSetRevisionSelector selects pods of daemon set's latest controller revision,
+// returning nil when there's no revision yet
+func daemonSetRevisionSelector(conn client.Client, ds *appsv1.DaemonSet) (*metav1.LabelSelector, error) {
+	if ds.Spec.Selector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(ds.Spec.Selector)
+	if err != nil {
+		return nil, err
+	}
+	revisions := appsv1.ControllerRevisionList{}
+	err = conn.List(context.TODO(), &revisions, client.InNamespace(ds.Namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+	var latest *appsv1.ControllerRevision
+	for i, r := range revisions.Items {
+		if metav1.IsControlledBy(&r, ds) && (latest == nil || r.Revision > latest.Revision) {
+			latest = &revisions.Items[i]
+		}
+	}
+	if latest == nil {
+		return nil, nil
+	}
+	hash, ok := latest.Labels[appsv1.DefaultDaemonSetUniqueLabelKey]
+	if !ok {
+		return nil, nil
+	}
+	return withRevisionLabel(ds.Spec.Selector, appsv1.DefaultDaemonSetUniqueLabelKey, hash), nil
+}
+
+// withRevisionLabel copies the selector, narrowing it to pods having given revision label
+func withRevisionLabel(labelSelector *metav1.LabelSelector, key, value string) *metav1.LabelSelector {
+	if labelSelector == nil {
+		return nil
+	}
+	out := labelSelector.DeepCopy()
+	if out.MatchLabels == nil {
+		out.MatchLabels = make(map[string]string)
+	}
+	out.MatchLabels[key] = value
+	return out
+}
+
+// DeploymentRolloutStatus reports if deployment is rolled out and describes its progress
+func DeploymentRolloutStatus(d *appsv1.Deployment) (bool, string, error) {
+	if d.Generation > d.Status.ObservedGeneration {
+		return false, "waiting for deployment spec update to be observed", nil
+	}
+
+	for _, c := range d.Status.Conditions {
+		if c.Type == appsv1.DeploymentProgressing && c.Reason == "ProgressDeadlineExceeded" {
+			return false, "", fmt.Errorf("deployment %q exceeded its progress deadline", d.Name)
+		}
+	}
+
+	replicas := int32(1)
+	if d.Spec.Replicas != nil {
+		replicas = *d.Spec.Replicas
+	}
+	if d.Status.UpdatedReplicas < replicas {
+		return false, fmt.Sprintf("%d out of %d new replicas have been updated",
+			d.Status.UpdatedReplicas, replicas), nil
+	}
+	if d.Status.Replicas > d.Status.UpdatedReplicas {
+		return false, fmt.Sprintf("%d old replicas are pending termination",
+			d.Status.Replicas-d.Status.UpdatedReplicas), nil
+	}
+	if d.Status.ReadyReplicas < d.Status.UpdatedReplicas {
+		return false, fmt.Sprintf("%d of %d updated replicas are ready",
+			d.Status.ReadyReplicas, d.Status.UpdatedReplicas), nil
+	}
+	if d.Status.AvailableReplicas < d.Status.UpdatedReplicas {
+		return false, fmt.Sprintf("%d of %d updated replicas are available",
+			d.Status.AvailableReplicas, d.Status.UpdatedReplicas), nil
+	}
+	return true, "", nil
+}
+
+// StatefulSetRolloutStatus reports if stateful set is rolled out and describes its progress
+func StatefulSetRolloutStatus(s *appsv1.StatefulSet) (bool, string, error) {
+	if s.Spec.UpdateStrategy.Type != appsv1.RollingUpdateStatefulSetStrategyType {
+		return false, "", fmt.Errorf("rollout status is only available for %s strategy type",
+			appsv1.RollingUpdateStatefulSetStrategyType)
+	}
+	if s.Status.ObservedGeneration == 0 || s.Generation > s.Status.ObservedGeneration {
+		return false, "waiting for statefulset spec update to be observed", nil
+	}
+
+	replicas := int32(1)
+	if s.Spec.Replicas != nil {
+		replicas = *s.Spec.Replicas
+	}
+	if s.Status.ReadyReplicas < replicas {
+		return false, fmt.Sprintf("%d of %d pods are ready", s.Status.ReadyReplicas, replicas), nil
+	}
+
+	if s.Spec.UpdateStrategy.RollingUpdate != nil && s.Spec.UpdateStrategy.RollingUpdate.Partition != nil {
+		partition := *s.Spec.UpdateStrategy.RollingUpdate.Partition
+		if s.Status.UpdatedReplicas < replicas-partition {
+			return false, fmt.Sprintf("waiting for partitioned roll out to finish: %d out of %d new pods have been updated",
+				s.Status.UpdatedReplicas, replicas-partition), nil
+		}
+		return true, "", nil
+	}
+
+	if s.Status.UpdateRevision != s.Status.CurrentRevision {
+		return false, fmt.Sprintf("waiting for rolling update to complete %d pods at revision %s",
+			s.Status.UpdatedReplicas, s.Status.UpdateRevision), nil
+	}
+	return true, "", nil
+}
+
+// DaemonSetRolloutStatus reports if daemon set is rolled out and describes its progress
+func DaemonSetRolloutStatus(ds *appsv1.DaemonSet) (bool, string, error) {
+	if ds.Spec.UpdateStrategy.Type != appsv1.RollingUpdateDaemonSetStrategyType {
+		return false, "", fmt.Errorf("rollout status is only available for %s strategy type",
+			appsv1.RollingUpdateDaemonSetStrategyType)
+	}
+	if ds.Generation > ds.Status.ObservedGeneration {
+		return false, "waiting for daemon set spec update to be observed", nil
+	}
+
+	desired := ds.Status.DesiredNumberScheduled
+	if ds.Status.UpdatedNumberScheduled < desired {
+		return false, fmt.Sprintf("%d out of %d new pods have been updated",
+			ds.Status.UpdatedNumberScheduled, desired), nil
+	}
+	if ds.Status.NumberReady < desired {
+		return false, fmt.Sprintf("%d of %d updated pods are ready",
+			ds.Status.NumberReady, desired), nil
+	}
+	if ds.Status.NumberAvailable < desired {
+		return false, fmt.Sprintf("%d of %d updated pods are available",
+			ds.Status.NumberAvailable, desired), nil
+	}
+	return true, "", nil
+}
+
+// withRolloutPodWarnings appends warnings of not ready pods selected by the workload to the error
+func withRolloutPodWarnings(conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, err error) error {
+	// Cancellation is returned as is, so callers can tell it apart
+	if err == context.Canceled || err == context.DeadlineExceeded {
+		return err
+	}
+	obj := &unstructured.Unstructured{}
+	obj.SetGroupVersionKind(gvk)
+	if getErr := conn.Get(context.TODO(), key, obj); getErr != nil {
+		log.Printf("[WARN] Failed to read %s to look up pod warnings: %s", key, getErr)
+		return err
+	}
+	m, found, _ := unstructured.NestedMap(obj.Object, "spec", "selector")
+	if !found {
+		return err
+	}
+	selector := &metav1.LabelSelector{}
+	if convErr := runtime.DefaultUnstructuredConverter.FromUnstructured(m, selector); convErr != nil {
+		log.Printf("[WARN] Failed to parse selector of %s: %s", key, convErr)
+		return err
+	}
+
+	warnings, wErr := getNotReadyPodsWarnings(conn, key.Namespace, selector)
+	if wErr != nil {
+		log.Printf("[WARN] Failed to look up pod warnings for %s: %s", key, wErr)
+		return err
+	}
+	if len(warnings) == 0 {
+		return err
+	}
+	return fmt.Errorf("%s%s", err, StringifyEvents(warnings))
+}
+
+// getNotReadyPodsWarnings returns latest warnings of not ready pods matching the selector
+func getNotReadyPodsWarnings(conn client.Client, namespace string, labelSelector *metav1.LabelSelector) ([]api.Event, error) {
+	if labelSelector == nil {
+		return nil, nil
+	}
+	selector, err := metav1.LabelSelectorAsSelector(labelSelector)
+	if err != nil {
+		return nil, err
+	}
+
+	pods := api.PodList{}
+	err = conn.List(context.TODO(), &pods, client.InNamespace(namespace), client.MatchingLabelsSelector{Selector: selector})
+	if err != nil {
+		return nil, err
+	}
+
+	var warnings []api.Event
+	podCount := 0
+	for _, pod := range pods.Items {
+		if podCount >= RolloutPodsLimit {
+			break
+		}
+		if isPodReady(&pod) {
+			continue
+		}
+		podCount++
+
+		podWarnings, err := GetLastWarningsForObject(conn, pod.ObjectMeta, "Pod", WaitWarningsLimit)
+		if err != nil {
+			return nil, err
+		}
+		warnings = append(warnings, podWarnings...)
+	}
+	return warnings, nil
+}
+
+func isPodReady(pod *api.Pod) bool {
+	for _, c := range pod.Status.Conditions {
+		if c.Type == api.PodReady {
+			return c.Status == api.ConditionTrue
+		}
+	}
+	return false
+}
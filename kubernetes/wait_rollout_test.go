@@ -0,0 +1,261 @@
+package kubernetes
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	appsv1 "k8s.io/api/apps/v1"
+	api "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func int32Ptr(i int32) *int32 {
+	return &i
+}
+
+func TestWaitForDeploymentRolloutCancelled(t *testing.T) {
+	selector := &metav1.LabelSelector{MatchLabels: map[string]string{"app": "web"}}
+	deployment := &appsv1.Deployment{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web", Generation: 1},
+		Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(1), Selector: selector},
+		Status:     appsv1.DeploymentStatus{ObservedGeneration: 1},
+	}
+	pod := &api.Pod{ObjectMeta: metav1.ObjectMeta{Namespace: "ns", Name: "web-1", Labels: map[string]string{"app": "web"}}}
+	warning := &api.Event{
+		ObjectMeta:     metav1.ObjectMeta{Namespace: "ns", Name: "web-1.1"},
+		InvolvedObject: api.ObjectReference{Kind: "Pod", Namespace: "ns", Name: "web-1"},
+		Type:           api.EventTypeWarning,
+		Reason:         "FailedScheduling",
+		Message:        "0/3 nodes are available",
+	}
+	conn := fake.NewFakeClientWithScheme(scheme.Scheme, deployment, pod, warning)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	err := WaitForDeploymentRollout(ctx, conn, client.ObjectKey{Namespace: "ns", Name: "web"}, time.Minute)
+	if err != context.Canceled {
+		t.Fatalf("Expected %v, got %v", context.Canceled, err)
+	}
+}
+
+type rolloutStatusCase struct {
+	name  string
+	done  bool
+	state string
+	err   string
+}
+
+func checkRolloutStatus(t *testing.T, c rolloutStatusCase, done bool, state string, err error) {
+	t.Helper()
+	if c.err != "" {
+		if err == nil || err.Error() != c.err {
+			t.Errorf("%s: expected error %q, got %v", c.name, c.err, err)
+		}
+		return
+	}
+	if err != nil {
+		t.Errorf("%s: unexpected error: %s", c.name, err)
+		return
+	}
+	if done != c.done || state != c.state {
+		t.Errorf("%s: expected (%t, %q), got (%t, %q)", c.name, c.done, c.state, done, state)
+	}
+}
+
+func TestDeploymentRolloutStatus(t *testing.T) {
+	deployment := func(set func(d *appsv1.Deployment)) *appsv1.Deployment {
+		d := &appsv1.Deployment{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Generation: 2},
+			Spec:       appsv1.DeploymentSpec{Replicas: int32Ptr(3)},
+			Status: appsv1.DeploymentStatus{
+				ObservedGeneration: 2,
+				Replicas:           3,
+				UpdatedReplicas:    3,
+				ReadyReplicas:      3,
+				AvailableReplicas:  3,
+			},
+		}
+		set(d)
+		return d
+	}
+
+	cases := []struct {
+		rolloutStatusCase
+		deployment *appsv1.Deployment
+	}{
+		{
+			rolloutStatusCase{name: "observed generation lag", state: "waiting for deployment spec update to be observed"},
+			deployment(func(d *appsv1.Deployment) { d.Status.ObservedGeneration = 1 }),
+		},
+		{
+			rolloutStatusCase{name: "progress deadline exceeded", err: `deployment "web" exceeded its progress deadline`},
+			deployment(func(d *appsv1.Deployment) {
+				d.Status.UpdatedReplicas = 1
+				d.Status.Conditions = []appsv1.DeploymentCondition{{
+					Type:   appsv1.DeploymentProgressing,
+					Status: api.ConditionFalse,
+					Reason: "ProgressDeadlineExceeded",
+				}}
+			}),
+		},
+		{
+			rolloutStatusCase{name: "updating", state: "1 out of 3 new replicas have been updated"},
+			deployment(func(d *appsv1.Deployment) { d.Status.UpdatedReplicas = 1 }),
+		},
+		{
+			rolloutStatusCase{name: "old replicas terminating", state: "1 old replicas are pending termination"},
+			deployment(func(d *appsv1.Deployment) { d.Status.Replicas = 4 }),
+		},
+		{
+			rolloutStatusCase{name: "not ready", state: "2 of 3 updated replicas are ready"},
+			deployment(func(d *appsv1.Deployment) { d.Status.ReadyReplicas = 2 }),
+		},
+		{
+			rolloutStatusCase{name: "not available", state: "2 of 3 updated replicas are available"},
+			deployment(func(d *appsv1.Deployment) { d.Status.AvailableReplicas = 2 }),
+		},
+		{
+			rolloutStatusCase{name: "rolled out", done: true},
+			deployment(func(d *appsv1.Deployment) {}),
+		},
+	}
+	for _, c := range cases {
+		done, state, err := DeploymentRolloutStatus(c.deployment)
+		checkRolloutStatus(t, c.rolloutStatusCase, done, state, err)
+	}
+}
+
+func TestStatefulSetRolloutStatus(t *testing.T) {
+	statefulSet := func(set func(s *appsv1.StatefulSet)) *appsv1.StatefulSet {
+		s := &appsv1.StatefulSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "db", Generation: 2},
+			Spec: appsv1.StatefulSetSpec{
+				Replicas:       int32Ptr(3),
+				UpdateStrategy: appsv1.StatefulSetUpdateStrategy{Type: appsv1.RollingUpdateStatefulSetStrategyType},
+			},
+			Status: appsv1.StatefulSetStatus{
+				ObservedGeneration: 2,
+				ReadyReplicas:      3,
+				UpdatedReplicas:    3,
+				CurrentRevision:    "db-2",
+				UpdateRevision:     "db-2",
+			},
+		}
+		set(s)
+		return s
+	}
+	partition := func(p int32) func(s *appsv1.StatefulSet) {
+		return func(s *appsv1.StatefulSet) {
+			s.Spec.UpdateStrategy.RollingUpdate = &appsv1.RollingUpdateStatefulSetStrategy{Partition: int32Ptr(p)}
+			s.Status.CurrentRevision = "db-1"
+		}
+	}
+
+	cases := []struct {
+		rolloutStatusCase
+		statefulSet *appsv1.StatefulSet
+	}{
+		{
+			rolloutStatusCase{name: "on delete strategy", err: "rollout status is only available for RollingUpdate strategy type"},
+			statefulSet(func(s *appsv1.StatefulSet) { s.Spec.UpdateStrategy.Type = appsv1.OnDeleteStatefulSetStrategyType }),
+		},
+		{
+			rolloutStatusCase{name: "observed generation lag", state: "waiting for statefulset spec update to be observed"},
+			statefulSet(func(s *appsv1.StatefulSet) { s.Status.ObservedGeneration = 1 }),
+		},
+		{
+			rolloutStatusCase{name: "not ready", state: "2 of 3 pods are ready"},
+			statefulSet(func(s *appsv1.StatefulSet) { s.Status.ReadyReplicas = 2 }),
+		},
+		{
+			rolloutStatusCase{name: "partitioned in progress", state: "waiting for partitioned roll out to finish: 1 out of 2 new pods have been updated"},
+			statefulSet(func(s *appsv1.StatefulSet) {
+				partition(1)(s)
+				s.Status.UpdatedReplicas = 1
+			}),
+		},
+		{
+			rolloutStatusCase{name: "partitioned rolled out", done: true},
+			statefulSet(func(s *appsv1.StatefulSet) {
+				partition(1)(s)
+				s.Status.UpdatedReplicas = 2
+			}),
+		},
+		{
+			rolloutStatusCase{name: "update revision differs", state: "waiting for rolling update to complete 2 pods at revision db-3"},
+			statefulSet(func(s *appsv1.StatefulSet) {
+				s.Status.UpdatedReplicas = 2
+				s.Status.UpdateRevision = "db-3"
+			}),
+		},
+		{
+			rolloutStatusCase{name: "rolled out", done: true},
+			statefulSet(func(s *appsv1.StatefulSet) {}),
+		},
+	}
+	for _, c := range cases {
+		done, state, err := StatefulSetRolloutStatus(c.statefulSet)
+		checkRolloutStatus(t, c.rolloutStatusCase, done, state, err)
+	}
+}
+
+func TestDaemonSetRolloutStatus(t *testing.T) {
+	daemonSet := func(set func(ds *appsv1.DaemonSet)) *appsv1.DaemonSet {
+		ds := &appsv1.DaemonSet{
+			ObjectMeta: metav1.ObjectMeta{Name: "agent", Generation: 2},
+			Spec: appsv1.DaemonSetSpec{
+				UpdateStrategy: appsv1.DaemonSetUpdateStrategy{Type: appsv1.RollingUpdateDaemonSetStrategyType},
+			},
+			Status: appsv1.DaemonSetStatus{
+				ObservedGeneration:     2,
+				DesiredNumberScheduled: 3,
+				UpdatedNumberScheduled: 3,
+				NumberReady:            3,
+				NumberAvailable:        3,
+			},
+		}
+		set(ds)
+		return ds
+	}
+
+	cases := []struct {
+		rolloutStatusCase
+		daemonSet *appsv1.DaemonSet
+	}{
+		{
+			rolloutStatusCase{name: "on delete strategy", err: "rollout status is only available for RollingUpdate strategy type"},
+			daemonSet(func(ds *appsv1.DaemonSet) { ds.Spec.UpdateStrategy.Type = appsv1.OnDeleteDaemonSetStrategyType }),
+		},
+		{
+			rolloutStatusCase{name: "observed generation lag", state: "waiting for daemon set spec update to be observed"},
+			daemonSet(func(ds *appsv1.DaemonSet) { ds.Status.ObservedGeneration = 1 }),
+		},
+		{
+			rolloutStatusCase{name: "updating", state: "1 out of 3 new pods have been updated"},
+			daemonSet(func(ds *appsv1.DaemonSet) { ds.Status.UpdatedNumberScheduled = 1 }),
+		},
+		{
+			rolloutStatusCase{name: "not ready", state: "2 of 3 updated pods are ready"},
+			daemonSet(func(ds *appsv1.DaemonSet) { ds.Status.NumberReady = 2 }),
+		},
+		{
+			rolloutStatusCase{name: "not available", state: "2 of 3 updated pods are available"},
+			daemonSet(func(ds *appsv1.DaemonSet) { ds.Status.NumberAvailable = 2 }),
+		},
+		{
+			rolloutStatusCase{name: "rolled out", done: true},
+			daemonSet(func(ds *appsv1.DaemonSet) {}),
+		},
+	}
+	for _, c := range cases {
+		done, state, err := DaemonSetRolloutStatus(c.daemonSet)
+		checkRolloutStatus(t, c.rolloutStatusCase, done, state, err)
+	}
+}
@@ -0,0 +1,206 @@
+package kubernetes
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+// WaitSpec describes when the object is considered ready, all criteria have to be met
+type WaitSpec struct {
+	Conditions []ConditionSpec
+	Fields     []FieldSpec
+	// Rollout waits for Deployment, StatefulSet or DaemonSet rollout to complete
+	Rollout bool
+	Timeout time.Duration
+}
+
+// FieldSpec describes field which has to be equal to the value
+type FieldSpec struct {
+	Path  FieldPath
+	Value string
+}
+
+// WaitFor waits until object meets all criteria of the spec
+func WaitFor(ctx context.Context, conn client.Client, key client.ObjectKey, gvk apimachineryschema.GroupVersionKind, spec *WaitSpec) error {
+	if spec == nil {
+		return nil
+	}
+
+	err := waitForObject(ctx, conn, key, gvk, spec.Timeout, func(obj *unstructured.Unstructured) (bool, string, error) {
+		return spec.Check(conn, obj)
+	})
+	if err != nil && spec.Rollout {
+		return withRolloutPodWarnings(conn, key, gvk, err)
+	}
+	return err
+}
+
+// Check reports if object meets all criteria of the spec and describes the first one which isn't met
+func (spec *WaitSpec) Check(conn client.Client, obj *unstructured.Unstructured) (bool, string, error) {
+	for _, c := range spec.Conditions {
+		done, state, err := c.Check(obj)
+		if !done || err != nil {
+			return done, state, err
+		}
+	}
+
+	for _, f := range spec.Fields {
+		done, state := f.Check(obj)
+		if !done {
+			return false, state, nil
+		}
+	}
+
+	if spec.Rollout {
+		return RolloutStatus(conn, obj)
+	}
+	return true, "", nil
+}
+
+// Check reports if object's field is equal to the value
+func (f FieldSpec) Check(obj *unstructured.Unstructured) (bool, string) {
+	v, found := f.Path.Get(obj.Object)
+	if !found {
+		return false, fmt.Sprintf("field %s is not set", f.Path)
+	}
+	value := stringifyFieldValue(v)
+	if value != f.Value {
+		return false, fmt.Sprintf("field %s is %q (expected %q)", f.Path, value, f.Value)
+	}
+	return true, ""
+}
+
+func stringifyFieldValue(v interface{}) string {
+	switch value := v.(type) {
+	case nil:
+		return ""
+	case string:
+		return value
+	case map[string]interface{}, []interface{}:
+		b, _ := json.Marshal(value)
+		return string(b)
+	default:
+		return fmt.Sprintf("%v", value)
+	}
+}
+
+// fieldPathSegment is either map key or list index
+type fieldPathSegment struct {
+	key     string
+	index   int
+	isIndex bool
+}
+
+// FieldPath is parsed JSONPath-like path of the field,
+// e.g. status.phase, {.status.containerStatuses[0].ready} or metadata.labels['app.kubernetes.io/name']
+type FieldPath []fieldPathSegment
+
+// ParseFieldPath parses JSONPath-like path of the field
+func ParseFieldPath(path string) (FieldPath, error) {
+	p := strings.TrimSpace(path)
+	if strings.HasPrefix(p, "{") {
+		if !strings.HasSuffix(p, "}") {
+			return nil, fmt.Errorf("missing closing brace")
+		}
+		p = strings.TrimSuffix(strings.TrimPrefix(p, "{"), "}")
+	}
+	p = strings.TrimPrefix(p, ".")
+	if p == "" {
+		return nil, fmt.Errorf("path is empty")
+	}
+
+	var out FieldPath
+	for i := 0; i < len(p); {
+		switch p[i] {
+		case '.':
+			if i+1 >= len(p) || p[i+1] == '.' || p[i+1] == '[' {
+				return nil, fmt.Errorf("empty segment at position %d", i+1)
+			}
+			i++
+		case '[':
+			if i+1 < len(p) && (p[i+1] == '\'' || p[i+1] == '"') {
+				// Quoted key ends with the quote followed by the bracket, so it may contain brackets
+				end := strings.Index(p[i+2:], string(p[i+1])+"]")
+				if end < 0 {
+					return nil, fmt.Errorf("missing closing quote at position %d", i+1)
+				}
+				out = append(out, fieldPathSegment{key: p[i+2 : i+2+end]})
+				i += end + 4
+			} else {
+				end := strings.IndexByte(p[i:], ']')
+				if end < 0 {
+					return nil, fmt.Errorf("missing closing bracket at position %d", i)
+				}
+				inner := p[i+1 : i+end]
+				index, err := strconv.Atoi(inner)
+				if err != nil || index < 0 {
+					return nil, fmt.Errorf("invalid index %q at position %d", inner, i)
+				}
+				out = append(out, fieldPathSegment{index: index, isIndex: true})
+				i += end + 1
+			}
+			if i < len(p) && p[i] != '.' && p[i] != '[' {
+				return nil, fmt.Errorf("unexpected character %q at position %d", p[i], i)
+			}
+		default:
+			end := strings.IndexAny(p[i:], ".[]")
+			if end < 0 {
+				end = len(p) - i
+			} else if p[i+end] == ']' {
+				return nil, fmt.Errorf("unexpected closing bracket at position %d", i+end)
+			}
+			out = append(out, fieldPathSegment{key: p[i : i+end]})
+			i += end
+		}
+	}
+	return out, nil
+}
+
+// Get looks up the field in unstructured object
+func (path FieldPath) Get(obj map[string]interface{}) (interface{}, bool) {
+	var current interface{} = obj
+	for _, s := range path {
+		if s.isIndex {
+			l, ok := current.([]interface{})
+			if !ok || s.index >= len(l) {
+				return nil, false
+			}
+			current = l[s.index]
+			continue
+		}
+		m, ok := current.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		current, ok = m[s.key]
+		if !ok {
+			return nil, false
+		}
+	}
+	return current, true
+}
+
+func (path FieldPath) String() string {
+	var out string
+	for _, s := range path {
+		switch {
+		case s.isIndex:
+			out += fmt.Sprintf("[%d]", s.index)
+		case strings.Contains(s.key, "'"):
+			out += fmt.Sprintf(`["%s"]`, s.key)
+		case s.key == "" || strings.ContainsAny(s.key, ".[]"):
+			out += fmt.Sprintf("['%s']", s.key)
+		default:
+			out += "." + s.key
+		}
+	}
+	return strings.TrimPrefix(out, ".")
+}
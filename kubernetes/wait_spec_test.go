@@ -0,0 +1,89 @@
+package kubernetes
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseFieldPath(t *testing.T) {
+	cases := []struct {
+		path   string
+		parsed FieldPath
+		str    string
+	}{
+		{
+			path:   "status.phase",
+			parsed: FieldPath{{key: "status"}, {key: "phase"}},
+			str:    "status.phase",
+		},
+		{
+			path:   "{.status.containerStatuses[0].ready}",
+			parsed: FieldPath{{key: "status"}, {key: "containerStatuses"}, {index: 0, isIndex: true}, {key: "ready"}},
+			str:    "status.containerStatuses[0].ready",
+		},
+		{
+			path:   "metadata.labels['app.kubernetes.io/name']",
+			parsed: FieldPath{{key: "metadata"}, {key: "labels"}, {key: "app.kubernetes.io/name"}},
+			str:    "metadata.labels['app.kubernetes.io/name']",
+		},
+		{
+			path:   `data["it's"]`,
+			parsed: FieldPath{{key: "data"}, {key: "it's"}},
+			str:    `data["it's"]`,
+		},
+		{
+			path:   "data['a]b']",
+			parsed: FieldPath{{key: "data"}, {key: "a]b"}},
+			str:    "data['a]b']",
+		},
+		{
+			path:   "[0][1]",
+			parsed: FieldPath{{index: 0, isIndex: true}, {index: 1, isIndex: true}},
+			str:    "[0][1]",
+		},
+	}
+
+	for _, c := range cases {
+		parsed, err := ParseFieldPath(c.path)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %s", c.path, err)
+			continue
+		}
+		if !reflect.DeepEqual(parsed, c.parsed) {
+			t.Errorf("%s: expected %#v, got %#v", c.path, c.parsed, parsed)
+		}
+		if parsed.String() != c.str {
+			t.Errorf("%s: expected string %q, got %q", c.path, c.str, parsed.String())
+		}
+		reparsed, err := ParseFieldPath(parsed.String())
+		if err != nil || !reflect.DeepEqual(reparsed, parsed) {
+			t.Errorf("%s: string %q doesn't round trip: %#v, %v", c.path, parsed.String(), reparsed, err)
+		}
+	}
+}
+
+func TestParseFieldPathInvalid(t *testing.T) {
+	cases := []struct {
+		path string
+		err  string
+	}{
+		{path: "", err: "path is empty"},
+		{path: "{.status.phase", err: "missing closing brace"},
+		{path: "status..phase", err: "empty segment at position 7"},
+		{path: "status.", err: "empty segment at position 7"},
+		{path: "status.conditions[0", err: "missing closing bracket at position 17"},
+		{path: "status.conditions[-1]", err: `invalid index "-1" at position 17`},
+		{path: "status.conditions[x]", err: `invalid index "x" at position 17`},
+		{path: "data['key]", err: "missing closing quote at position 5"},
+		{path: "data.a]b", err: "unexpected closing bracket at position 6"},
+		{path: "status.conditions[0]type", err: `unexpected character 't' at position 20`},
+	}
+
+	for _, c := range cases {
+		_, err := ParseFieldPath(c.path)
+		if err == nil || !strings.Contains(err.Error(), c.err) {
+			t.Errorf("%q: expected error %q, got %v", c.path, c.err, err)
+		}
+	}
+}
@@ -0,0 +1,348 @@
+package kubernetes
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	apimachineryschema "k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/wait"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/apiutil"
+)
+
+// errWatchUnavailable is returned when watch can't be established, e.g. due to missing permissions
+var errWatchUnavailable = errors.New("watch is unavailable")
+
+// WatchingClient is client.Client which makes wait helpers watch objects
+// via watch streams shared between concurrent waiters instead of polling
+type WatchingClient struct {
+	client.Client
+	watcher *watcher
+}
+
+// NewWatchingClient wraps the client so wait helpers use watch streams.
+// Wait helpers fall back to polling when watch isn't permitted.
+func NewWatchingClient(conn client.Client, cfg *rest.Config) (*WatchingClient, error) {
+	dynamicClient, err := dynamic.NewForConfig(cfg)
+	if err != nil {
+		return nil, err
+	}
+	mapper, err := apiutil.NewDynamicRESTMapper(cfg)
+	if err != nil {
+		return nil, err
+	}
+	return &WatchingClient{
+		Client: conn,
+		watcher: &watcher{
+			dynamic: dynamicClient,
+			mapper:  mapper,
+			streams: make(map[watchStreamKey]*watchStream),
+		},
+	}, nil
+}
+
+// watcher keeps one watch stream per GVK and namespace shared by all waiters
+type watcher struct {
+	dynamic dynamic.Interface
+	mapper  meta.RESTMapper
+
+	mu      sync.Mutex
+	streams map[watchStreamKey]*watchStream
+}
+
+type watchStreamKey struct {
+	gvk       apimachineryschema.GroupVersionKind
+	namespace string
+}
+
+// watchStream is informer-like cache of objects kept up to date by a watch
+type watchStream struct {
+	key      watchStreamKey
+	resource dynamic.ResourceInterface
+	cancel   context.CancelFunc
+
+	// synced is closed once initial list and watch are done, err is set if they failed
+	synced chan struct{}
+	err    error
+
+	mu          sync.Mutex
+	objects     map[string]*unstructured.Unstructured
+	subscribers map[*watchSubscriber]struct{}
+}
+
+type watchSubscriber struct {
+	name    string
+	changed chan struct{}
+}
+
+// waitFor evaluates cond every time watched object changes until it's done,
+// fails, context is cancelled or timeout is reached. Object is nil when it doesn't exist.
+func (w *watcher) waitFor(ctx context.Context, gvk apimachineryschema.GroupVersionKind, key client.ObjectKey, timeout time.Duration, cond func(obj *unstructured.Unstructured) (bool, error)) error {
+	timeoutCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	stream, sub, err := w.subscribe(timeoutCtx, gvk, key)
+	if err != nil {
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		// Initial list or sync didn't finish in time, polling wouldn't do better
+		if timeoutCtx.Err() != nil {
+			return errWaitTimeout
+		}
+		log.Printf("[DEBUG] Unable to watch %s %s, falling back to polling: %s", gvk.Kind, key, err)
+		return errWatchUnavailable
+	}
+	defer w.unsubscribe(stream, sub)
+
+	for {
+		done, err := cond(stream.get(key.Name))
+		if err != nil {
+			return err
+		}
+		if done {
+			return nil
+		}
+
+		select {
+		case <-timeoutCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return errWaitTimeout
+		case <-sub.changed:
+		}
+	}
+}
+
+func (w *watcher) subscribe(ctx context.Context, gvk apimachineryschema.GroupVersionKind, key client.ObjectKey) (*watchStream, *watchSubscriber, error) {
+	mapping, err := w.mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return nil, nil, err
+	}
+	namespace := key.Namespace
+	if mapping.Scope.Name() == meta.RESTScopeNameRoot {
+		namespace = ""
+	}
+	streamKey := watchStreamKey{gvk: gvk, namespace: namespace}
+	sub := &watchSubscriber{name: key.Name, changed: make(chan struct{}, 1)}
+
+	w.mu.Lock()
+	stream, ok := w.streams[streamKey]
+	if !ok {
+		streamCtx, cancel := context.WithCancel(context.Background())
+		stream = &watchStream{
+			key:         streamKey,
+			resource:    w.dynamic.Resource(mapping.Resource).Namespace(namespace),
+			cancel:      cancel,
+			synced:      make(chan struct{}),
+			objects:     make(map[string]*unstructured.Unstructured),
+			subscribers: make(map[*watchSubscriber]struct{}),
+		}
+		w.streams[streamKey] = stream
+		go stream.run(streamCtx)
+	}
+	stream.mu.Lock()
+	stream.subscribers[sub] = struct{}{}
+	stream.mu.Unlock()
+	w.mu.Unlock()
+
+	select {
+	case <-stream.synced:
+	case <-ctx.Done():
+		w.unsubscribe(stream, sub)
+		return nil, nil, ctx.Err()
+	}
+	if stream.err != nil {
+		w.unsubscribe(stream, sub)
+		return nil, nil, stream.err
+	}
+	return stream, sub, nil
+}
+
+// unsubscribe removes the subscriber, stopping the stream when nobody listens anymore
+func (w *watcher) unsubscribe(stream *watchStream, sub *watchSubscriber) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+
+	stream.mu.Lock()
+	delete(stream.subscribers, sub)
+	empty := len(stream.subscribers) == 0
+	stream.mu.Unlock()
+
+	if empty && w.streams[stream.key] == stream {
+		delete(w.streams, stream.key)
+		stream.cancel()
+	}
+}
+
+func (s *watchStream) get(name string) *unstructured.Unstructured {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if obj, ok := s.objects[name]; ok {
+		return obj.DeepCopy()
+	}
+	return nil
+}
+
+// run lists objects and watches them, resuming from last seen resourceVersion
+// when watch is closed and re-listing when resourceVersion is too old (410 Gone)
+func (s *watchStream) run(ctx context.Context) {
+	backoff := WaitBackoff
+	resourceVersion := ""
+	first := true
+	for ctx.Err() == nil {
+		if resourceVersion == "" {
+			rv, err := s.list()
+			if err != nil {
+				if s.fail(first, err) {
+					return
+				}
+				s.sleep(ctx, &backoff)
+				continue
+			}
+			resourceVersion = rv
+		}
+
+		w, err := s.resource.Watch(metav1.ListOptions{
+			ResourceVersion:     resourceVersion,
+			AllowWatchBookmarks: true,
+		})
+		if err != nil {
+			if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+				resourceVersion = ""
+				continue
+			}
+			if s.fail(first, err) {
+				return
+			}
+			s.sleep(ctx, &backoff)
+			continue
+		}
+		if first {
+			first = false
+			close(s.synced)
+		}
+		backoff = WaitBackoff
+
+		resourceVersion = s.consume(ctx, w, resourceVersion)
+	}
+}
+
+// consume processes watch events until watch is closed, returning resourceVersion
+// to resume from, which is empty when objects have to be re-listed
+func (s *watchStream) consume(ctx context.Context, w watch.Interface, resourceVersion string) string {
+	defer w.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return resourceVersion
+		case ev, ok := <-w.ResultChan():
+			if !ok {
+				return resourceVersion
+			}
+			if ev.Type == watch.Error {
+				err := apierrors.FromObject(ev.Object)
+				if apierrors.IsGone(err) || apierrors.IsResourceExpired(err) {
+					log.Printf("[DEBUG] Watch of %s is too old, re-listing", s.key.gvk.Kind)
+					return ""
+				}
+				log.Printf("[DEBUG] Watch of %s failed: %s", s.key.gvk.Kind, err)
+				return resourceVersion
+			}
+
+			obj, ok := ev.Object.(*unstructured.Unstructured)
+			if !ok {
+				continue
+			}
+			resourceVersion = obj.GetResourceVersion()
+
+			switch ev.Type {
+			case watch.Added, watch.Modified:
+				s.store(obj)
+			case watch.Deleted:
+				s.remove(obj.GetName())
+			}
+		}
+	}
+}
+
+// list replaces cached objects with current ones, returning list resourceVersion
+func (s *watchStream) list() (string, error) {
+	list, err := s.resource.List(metav1.ListOptions{})
+	if err != nil {
+		return "", err
+	}
+
+	objects := make(map[string]*unstructured.Unstructured, len(list.Items))
+	for i := range list.Items {
+		objects[list.Items[i].GetName()] = &list.Items[i]
+	}
+
+	s.mu.Lock()
+	s.objects = objects
+	for sub := range s.subscribers {
+		sub.notify()
+	}
+	s.mu.Unlock()
+
+	return list.GetResourceVersion(), nil
+}
+
+func (s *watchStream) store(obj *unstructured.Unstructured) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.objects[obj.GetName()] = obj
+	s.notify(obj.GetName())
+}
+
+func (s *watchStream) remove(name string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.objects, name)
+	s.notify(name)
+}
+
+// notify wakes up subscribers of given object, must be called with the lock held
+func (s *watchStream) notify(name string) {
+	for sub := range s.subscribers {
+		if sub.name == name {
+			sub.notify()
+		}
+	}
+}
+
+// fail handles error of list or watch call, stopping the stream when it isn't established yet
+func (s *watchStream) fail(first bool, err error) bool {
+	if !first {
+		log.Printf("[DEBUG] Watch of %s failed, retrying: %s", s.key.gvk.Kind, err)
+		return false
+	}
+	s.err = err
+	close(s.synced)
+	return true
+}
+
+func (s *watchStream) sleep(ctx context.Context, backoff *wait.Backoff) {
+	select {
+	case <-ctx.Done():
+	case <-time.After(backoff.Step()):
+	}
+}
+
+func (sub *watchSubscriber) notify() {
+	select {
+	case sub.changed <- struct{}{}:
+	default:
+	}
+}
@@ -0,0 +1,214 @@
+package kubernetes
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	"k8s.io/client-go/kubernetes/scheme"
+	clienttesting "k8s.io/client-go/testing"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+var testWidgetGVR = testWidgetGVK.GroupVersion().WithResource("widgets")
+
+// newTestWatcher returns watcher backed by fake dynamic client counting list and watch calls.
+// Watch calls are passed to watchReaction with number of the call, falling back
+// to the fake object tracker when it isn't handled.
+func newTestWatcher(lists, watches *int32, watchReaction func(n int32) (bool, watch.Interface, error), objs ...runtime.Object) (*watcher, *dynamicfake.FakeDynamicClient) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClient(runtime.NewScheme(), objs...)
+	dynamicClient.PrependReactor("list", "widgets", func(clienttesting.Action) (bool, runtime.Object, error) {
+		atomic.AddInt32(lists, 1)
+		return false, nil, nil
+	})
+	dynamicClient.PrependWatchReactor("widgets", func(clienttesting.Action) (bool, watch.Interface, error) {
+		return watchReaction(atomic.AddInt32(watches, 1))
+	})
+
+	mapper := meta.NewDefaultRESTMapper(nil)
+	mapper.Add(testWidgetGVK, meta.RESTScopeNamespace)
+	return &watcher{
+		dynamic: dynamicClient,
+		mapper:  mapper,
+		streams: make(map[watchStreamKey]*watchStream),
+	}, dynamicClient
+}
+
+func newNamedTestWidget(name, phase string) *unstructured.Unstructured {
+	obj := newTestWidget()
+	obj.SetName(name)
+	unstructured.SetNestedField(obj.Object, phase, "status", "phase")
+	return obj
+}
+
+// waitForTestWidget waits in background until the widget is ready, signalling subscribed
+// on the first evaluation of the condition
+func waitForTestWidget(w *watcher, name string, subscribed chan<- struct{}) <-chan error {
+	result := make(chan error, 1)
+	first := true
+	go func() {
+		key := client.ObjectKey{Namespace: "ns", Name: name}
+		result <- w.waitFor(context.Background(), testWidgetGVK, key, 5*time.Second, func(obj *unstructured.Unstructured) (bool, error) {
+			if first {
+				first = false
+				subscribed <- struct{}{}
+			}
+			if obj == nil {
+				return false, nil
+			}
+			phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+			return phase == "Ready", nil
+		})
+	}()
+	return result
+}
+
+func receive(t *testing.T, ch <-chan error) error {
+	t.Helper()
+	select {
+	case err := <-ch:
+		return err
+	case <-time.After(5 * time.Second):
+		t.Fatal("Timeout while waiting for the waiter")
+		return nil
+	}
+}
+
+func streamCount(w *watcher) int {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return len(w.streams)
+}
+
+func TestWatcherSharesStream(t *testing.T) {
+	var lists, watches int32
+	fakeWatch := watch.NewFake()
+	w, _ := newTestWatcher(&lists, &watches, func(int32) (bool, watch.Interface, error) {
+		return true, fakeWatch, nil
+	}, newNamedTestWidget("first", "Pending"), newNamedTestWidget("second", "Pending"))
+
+	subscribed := make(chan struct{}, 2)
+	first := waitForTestWidget(w, "first", subscribed)
+	<-subscribed
+	second := waitForTestWidget(w, "second", subscribed)
+	<-subscribed
+
+	fakeWatch.Modify(newNamedTestWidget("first", "Ready"))
+	fakeWatch.Modify(newNamedTestWidget("second", "Ready"))
+	if err := receive(t, first); err != nil {
+		t.Errorf("Unexpected error of the first waiter: %s", err)
+	}
+	if err := receive(t, second); err != nil {
+		t.Errorf("Unexpected error of the second waiter: %s", err)
+	}
+
+	if atomic.LoadInt32(&lists) != 1 || atomic.LoadInt32(&watches) != 1 {
+		t.Errorf("Expected waiters to share one stream, got %d lists and %d watches", lists, watches)
+	}
+}
+
+func TestWatcherStopsStreamAfterLastUnsubscribe(t *testing.T) {
+	var lists, watches int32
+	fakeWatch := watch.NewFake()
+	w, _ := newTestWatcher(&lists, &watches, func(int32) (bool, watch.Interface, error) {
+		return true, fakeWatch, nil
+	}, newNamedTestWidget("first", "Pending"), newNamedTestWidget("second", "Pending"))
+
+	subscribed := make(chan struct{}, 2)
+	first := waitForTestWidget(w, "first", subscribed)
+	<-subscribed
+	second := waitForTestWidget(w, "second", subscribed)
+	<-subscribed
+
+	fakeWatch.Modify(newNamedTestWidget("first", "Ready"))
+	if err := receive(t, first); err != nil {
+		t.Fatalf("Unexpected error of the first waiter: %s", err)
+	}
+	if streamCount(w) != 1 || fakeWatch.IsStopped() {
+		t.Fatal("Stream was stopped while it still had a subscriber")
+	}
+
+	fakeWatch.Modify(newNamedTestWidget("second", "Ready"))
+	if err := receive(t, second); err != nil {
+		t.Fatalf("Unexpected error of the second waiter: %s", err)
+	}
+	if streamCount(w) != 0 {
+		t.Error("Stream wasn't removed after the last unsubscribe")
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for !fakeWatch.IsStopped() {
+		if time.Now().After(deadline) {
+			t.Fatal("Watch wasn't stopped after the last unsubscribe")
+		}
+		time.Sleep(time.Millisecond)
+	}
+}
+
+func TestWatcherFallsBackToPolling(t *testing.T) {
+	var lists, watches int32
+	w, _ := newTestWatcher(&lists, &watches, func(int32) (bool, watch.Interface, error) {
+		return true, nil, apierrors.NewForbidden(testWidgetGVR.GroupResource(), "", nil)
+	}, newNamedTestWidget("widget", "Pending"))
+	conn := &WatchingClient{
+		Client:  fake.NewFakeClientWithScheme(scheme.Scheme, newNamedTestWidget("widget", "Ready")),
+		watcher: w,
+	}
+
+	key := client.ObjectKey{Namespace: "ns", Name: "widget"}
+	err := w.waitFor(context.Background(), testWidgetGVK, key, time.Second, func(*unstructured.Unstructured) (bool, error) {
+		return false, nil
+	})
+	if err != errWatchUnavailable {
+		t.Fatalf("Expected watch to be unavailable, got %v", err)
+	}
+	if streamCount(w) != 0 {
+		t.Error("Failed stream wasn't removed")
+	}
+
+	// Object is only ready in the polled client, so the wait succeeds only by polling
+	err = waitForState(context.Background(), conn, key, testWidgetGVK, time.Second, func(obj *unstructured.Unstructured) (bool, error) {
+		if obj == nil {
+			return false, nil
+		}
+		phase, _, _ := unstructured.NestedString(obj.Object, "status", "phase")
+		return phase == "Ready", nil
+	})
+	if err != nil {
+		t.Errorf("Expected wait to fall back to polling, got %s", err)
+	}
+}
+
+func TestWatcherRelistsAfterGone(t *testing.T) {
+	var lists, watches int32
+	fakeWatch := watch.NewFake()
+	w, dynamicClient := newTestWatcher(&lists, &watches, func(n int32) (bool, watch.Interface, error) {
+		return n == 1, fakeWatch, nil
+	}, newNamedTestWidget("widget", "Pending"))
+
+	subscribed := make(chan struct{}, 1)
+	result := waitForTestWidget(w, "widget", subscribed)
+	<-subscribed
+
+	// Change is missed by the watch and becomes visible only by re-listing
+	_, err := dynamicClient.Resource(testWidgetGVR).Namespace("ns").Update(newNamedTestWidget("widget", "Ready"), metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fakeWatch.Error(&apierrors.NewGone("too old resource version").ErrStatus)
+
+	if err := receive(t, result); err != nil {
+		t.Errorf("Unexpected error: %s", err)
+	}
+	if atomic.LoadInt32(&lists) != 2 {
+		t.Errorf("Expected objects to be re-listed after Gone, got %d lists", lists)
+	}
+}